@@ -20,6 +20,129 @@ type Config struct {
 	InvitationTTL  int
 	// BARU: Menambahkan URL RabbitMQ untuk koneksi ke message broker.
 	RabbitMQURL string
+
+	// Batas pengiriman ulang undangan: jumlah maksimum dan jeda minimum antar pengiriman.
+	InvitationResendMax             int
+	InvitationResendCooldownMinutes int
+
+	// InvitationReservationLeaseSeconds adalah lama reservasi pada alur terima dua fase.
+	InvitationReservationLeaseSeconds int
+
+	// Batas masa berlaku undangan yang dipilih pengundang, dalam jam. InvitationTTLBounds berisi
+	// pengecualian per tenant dalam format "tenant-a=24:168" (minimum:maksimum).
+	InvitationTTLMinHours int
+	InvitationTTLMaxHours int
+	InvitationTTLBounds   string
+
+	// Template tautan penerimaan undangan berisi {token}. InvitationLinkTemplates berisi
+	// pengecualian per tenant dalam format "tenant-a=https://join.acme.example/{token}" untuk
+	// domain white-label, staging atau instalasi on-prem. Setiap tautan harus URL HTTPS absolut.
+	InvitationLinkTemplate  string
+	InvitationLinkTemplates string
+
+	// Token undangan bertanda tangan. InvitationTokenKeys berisi kunci HMAC dalam format
+	// "kid1:base64,kid2:base64" dan dibaca dari environment karena bersifat rahasia;
+	// InvitationTokenActiveKey memilih kunci untuk token baru. Token UUID dipakai jika
+	// InvitationTokenKeys kosong. InvitationTokenAcceptLegacy tetap menerima token UUID lama
+	// selama masa migrasi.
+	InvitationTokenKeys         string
+	InvitationTokenActiveKey    string
+	InvitationTokenAcceptLegacy bool
+
+	// Pepper hash token undangan di Redis. InvitationTokenPeppers memakai format yang sama
+	// dengan InvitationTokenKeys dan dibaca dari environment; InvitationTokenActivePepper
+	// memilih pepper untuk token baru. Hash sha256 tanpa pepper dipakai jika keduanya kosong.
+	// InvitationTokenAcceptUnpeppered tetap mencari undangan lama yang disimpan tanpa pepper
+	// hingga semuanya kedaluwarsa.
+	InvitationTokenPeppers          string
+	InvitationTokenActivePepper     string
+	InvitationTokenAcceptUnpeppered bool
+
+	// Sumber kunci token undangan yang dimuat saat startup dan diperbarui setiap
+	// InvitationKeysRefreshSeconds. InvitationKeysVaultEngine "kv" membaca secret KV v2 di
+	// InvitationKeysVaultPath; "transit" mengekspor kunci HMAC dari mount Transit di path
+	// tersebut. InvitationKeysFile adalah berkas JSON lokal untuk pengembangan jika Vault
+	// tidak dipakai. Keduanya didahulukan dari InvitationTokenKeys dan InvitationTokenPeppers,
+	// dan harus berisi kunci penandatangan maupun pepper.
+	InvitationKeysVaultEngine    string
+	InvitationKeysVaultPath      string
+	InvitationKeysFile           string
+	InvitationKeysRefreshSeconds int
+
+	// InvitationExpirySweepSeconds adalah interval pemindaian undangan kedaluwarsa untuk event invitation.expired.
+	InvitationExpirySweepSeconds int
+
+	// Relay outbox notifikasi: interval pemeriksaan dan jumlah percobaan sebelum pesan dipindahkan ke dead letter.
+	OutboxPollIntervalSeconds int
+	OutboxMaxAttempts         int
+
+	// Perilaku publisher notifikasi RabbitMQ saat koneksi terputus: "fail_fast" langsung
+	// menolak publish dan menyerahkannya ke relay outbox, "buffer" menahan publish hingga
+	// koneksi pulih (dibatasi ukuran dan batas waktu singkat). Publisher domain event selalu
+	// fail-fast karena event bersifat best effort dan dikirim di jalur request.
+	RabbitMQDisconnectedMode            string
+	RabbitMQPublishBufferSize           int
+	RabbitMQPublishBufferTimeoutSeconds int
+
+	// InvitationJobWorkers adalah jumlah worker yang memproses job undangan massal di setiap replika.
+	InvitationJobWorkers int
+
+	// Validasi role undangan. Jika RoleServiceURL diisi, katalog role tenant dibaca dari service
+	// role/permission; jika tidak, InvitationRoles (dipisah koma, dari hak akses terendah ke
+	// tertinggi) dipakai untuk semua tenant. Validasi nonaktif jika keduanya kosong.
+	RoleServiceURL            string
+	RoleServiceTimeoutSeconds int
+	RoleCacheSeconds          int
+	InvitationRoles           string
+
+	// Kuota kursi paket tenant. Jika SeatServiceURL diisi, sisa kursi dibaca dari service
+	// billing; jika tidak, SeatLimitDefault berlaku untuk semua tenant dengan pengecualian
+	// SeatLimits ("tenant-a=50,tenant-b=10"). Nilai 0 berarti tanpa batas.
+	SeatServiceURL            string
+	SeatServiceTimeoutSeconds int
+	SeatLimitDefault          int
+	SeatLimits                string
+
+	// Pemeriksaan izin route undangan. Jika RBACUserServiceAddr diisi, izin role pemanggil
+	// diambil dari user-service melalui commonauth.RBACMiddleware dan di-cache selama
+	// RBACCacheTTLSeconds; jika tidak, izin dibaca dari klaim JWT PermissionsClaim, misalnya
+	// invitations:create.
+	RBACUserServiceAddr string
+	RBACCacheTTLSeconds int
+	PermissionsClaim    string
+
+	// TrustedProxies adalah daftar IP atau CIDR ingress yang boleh mengisi X-Forwarded-For,
+	// dipisah koma. Jika kosong, IP klien selalu diambil dari koneksi langsung sehingga
+	// penguncian TokenGuard dan metadata audit SourceIP tidak dapat dipalsukan lewat header.
+	TrustedProxies string
+
+	// Batas laju pembuatan undangan dalam jendela geser: per tenant, per pengundang, dan per
+	// domain email penerima. Batas bernilai 0 dinonaktifkan.
+	RateLimitWindowSeconds int
+	RateLimitPerTenant     int
+	RateLimitPerInviter    int
+	RateLimitPerDomain     int
+
+	// Pembatasan tebakan token pada endpoint publik: sebuah IP dikunci setelah
+	// TokenGuardMaxFailures token tidak valid dalam jendelanya, dengan lama penguncian berlipat
+	// dua setiap kali hingga batas maksimum. Seluruh endpoint dikunci sementara jika token tidak
+	// valid dari semua IP melampaui TokenGuardGlobalMaxFailures dalam jendela global.
+	TokenGuardWindowSeconds        int
+	TokenGuardMaxFailures          int
+	TokenGuardBaseLockoutSeconds   int
+	TokenGuardMaxLockoutSeconds    int
+	TokenGuardGlobalWindowSeconds  int
+	TokenGuardGlobalMaxFailures    int
+	TokenGuardGlobalLockoutSeconds int
+
+	// Kode undangan pendek untuk penerima tanpa akses tautan. ShortCodeLength termasuk karakter
+	// checksum. Percobaan kode memakai guard terpisah dengan ambang lebih rendah karena
+	// entropinya kecil; lama penguncian mengikuti service.DefaultShortCodeGuardLimits.
+	ShortCodesEnabled               bool
+	ShortCodeLength                 int
+	ShortCodeGuardWindowSeconds     int
+	ShortCodeGuardMaxFailures       int
+	ShortCodeGuardGlobalMaxFailures int
 }
 
 // Load memuat konfigurasi dari environment variables dan Consul.
@@ -32,6 +155,9 @@ func Load() *Config {
 	serviceName := "prism-invitation-service"
 	pathPrefix := fmt.Sprintf("config/%s", serviceName)
 
+	tokenAcceptLegacy, _ := strconv.ParseBool(loader.Get(fmt.Sprintf("%s/invitation_token_accept_legacy", pathPrefix), "true"))
+	shortCodesEnabled, _ := strconv.ParseBool(loader.Get(fmt.Sprintf("%s/short_codes_enabled", pathPrefix), "false"))
+	tokenAcceptUnpeppered, _ := strconv.ParseBool(loader.Get(fmt.Sprintf("%s/invitation_token_accept_unpeppered", pathPrefix), "true"))
 	invitationTTL, _ := strconv.Atoi(loader.Get(fmt.Sprintf("%s/invitation_ttl_hours", pathPrefix), "168")) // Default 7 hari
 
 	return &Config{
@@ -45,5 +171,75 @@ func Load() *Config {
 		// BARU: Memuat URL RabbitMQ dari environment variable. Ini adalah praktik umum
 		// karena URL koneksi sering kali berisi kredensial.
 		RabbitMQURL: os.Getenv("RABBITMQ_URL"),
+
+		InvitationResendMax:             loader.GetInt(fmt.Sprintf("%s/invitation_resend_max", pathPrefix), 3),
+		InvitationResendCooldownMinutes: loader.GetInt(fmt.Sprintf("%s/invitation_resend_cooldown_minutes", pathPrefix), 10),
+
+		InvitationReservationLeaseSeconds: loader.GetInt(fmt.Sprintf("%s/invitation_reservation_lease_seconds", pathPrefix), 300),
+
+		InvitationTTLMinHours: loader.GetInt(fmt.Sprintf("%s/invitation_ttl_min_hours", pathPrefix), 1),
+		InvitationTTLMaxHours: loader.GetInt(fmt.Sprintf("%s/invitation_ttl_max_hours", pathPrefix), 720),
+		InvitationTTLBounds:   loader.Get(fmt.Sprintf("%s/invitation_ttl_bounds", pathPrefix), ""),
+
+		InvitationLinkTemplate:  loader.Get(fmt.Sprintf("%s/invitation_link_template", pathPrefix), "https://app.prismerp.com/accept-invitation?token={token}"),
+		InvitationLinkTemplates: loader.Get(fmt.Sprintf("%s/invitation_link_templates", pathPrefix), ""),
+
+		InvitationTokenKeys:         os.Getenv("INVITATION_TOKEN_KEYS"),
+		InvitationTokenActiveKey:    loader.Get(fmt.Sprintf("%s/invitation_token_active_key", pathPrefix), ""),
+		InvitationTokenAcceptLegacy: tokenAcceptLegacy,
+
+		InvitationTokenPeppers:          os.Getenv("INVITATION_TOKEN_PEPPERS"),
+		InvitationTokenActivePepper:     loader.Get(fmt.Sprintf("%s/invitation_token_active_pepper", pathPrefix), ""),
+		InvitationTokenAcceptUnpeppered: tokenAcceptUnpeppered,
+
+		InvitationKeysVaultEngine:    loader.Get(fmt.Sprintf("%s/invitation_keys_vault_engine", pathPrefix), "kv"),
+		InvitationKeysVaultPath:      loader.Get(fmt.Sprintf("%s/invitation_keys_vault_path", pathPrefix), ""),
+		InvitationKeysFile:           loader.Get(fmt.Sprintf("%s/invitation_keys_file", pathPrefix), ""),
+		InvitationKeysRefreshSeconds: loader.GetInt(fmt.Sprintf("%s/invitation_keys_refresh_seconds", pathPrefix), 300),
+
+		InvitationExpirySweepSeconds: loader.GetInt(fmt.Sprintf("%s/invitation_expiry_sweep_seconds", pathPrefix), 300),
+
+		OutboxPollIntervalSeconds: loader.GetInt(fmt.Sprintf("%s/outbox_poll_interval_seconds", pathPrefix), 1),
+		OutboxMaxAttempts:         loader.GetInt(fmt.Sprintf("%s/outbox_max_attempts", pathPrefix), 10),
+
+		RabbitMQDisconnectedMode:            loader.Get(fmt.Sprintf("%s/rabbitmq_disconnected_mode", pathPrefix), "fail_fast"),
+		RabbitMQPublishBufferSize:           loader.GetInt(fmt.Sprintf("%s/rabbitmq_publish_buffer_size", pathPrefix), 100),
+		RabbitMQPublishBufferTimeoutSeconds: loader.GetInt(fmt.Sprintf("%s/rabbitmq_publish_buffer_timeout_seconds", pathPrefix), 2),
+
+		InvitationJobWorkers: loader.GetInt(fmt.Sprintf("%s/invitation_job_workers", pathPrefix), 4),
+
+		RoleServiceURL:            loader.Get(fmt.Sprintf("%s/role_service_url", pathPrefix), ""),
+		RoleServiceTimeoutSeconds: loader.GetInt(fmt.Sprintf("%s/role_service_timeout_seconds", pathPrefix), 5),
+		RoleCacheSeconds:          loader.GetInt(fmt.Sprintf("%s/role_cache_seconds", pathPrefix), 300),
+		InvitationRoles:           loader.Get(fmt.Sprintf("%s/invitation_roles", pathPrefix), ""),
+
+		SeatServiceURL:            loader.Get(fmt.Sprintf("%s/seat_service_url", pathPrefix), ""),
+		SeatServiceTimeoutSeconds: loader.GetInt(fmt.Sprintf("%s/seat_service_timeout_seconds", pathPrefix), 5),
+		SeatLimitDefault:          loader.GetInt(fmt.Sprintf("%s/seat_limit_default", pathPrefix), 0),
+		SeatLimits:                loader.Get(fmt.Sprintf("%s/seat_limits", pathPrefix), ""),
+
+		RBACUserServiceAddr: loader.Get(fmt.Sprintf("%s/rbac_user_service_addr", pathPrefix), ""),
+		RBACCacheTTLSeconds: loader.GetInt(fmt.Sprintf("%s/rbac_cache_ttl_seconds", pathPrefix), 300),
+		PermissionsClaim:    loader.Get(fmt.Sprintf("%s/permissions_claim", pathPrefix), "permissions"),
+		TrustedProxies:      loader.Get(fmt.Sprintf("%s/trusted_proxies", pathPrefix), ""),
+
+		RateLimitWindowSeconds: loader.GetInt(fmt.Sprintf("%s/rate_limit_window_seconds", pathPrefix), 3600),
+		RateLimitPerTenant:     loader.GetInt(fmt.Sprintf("%s/rate_limit_per_tenant", pathPrefix), 1000),
+		RateLimitPerInviter:    loader.GetInt(fmt.Sprintf("%s/rate_limit_per_inviter", pathPrefix), 200),
+		RateLimitPerDomain:     loader.GetInt(fmt.Sprintf("%s/rate_limit_per_domain", pathPrefix), 300),
+
+		TokenGuardWindowSeconds:        loader.GetInt(fmt.Sprintf("%s/token_guard_window_seconds", pathPrefix), 900),
+		TokenGuardMaxFailures:          loader.GetInt(fmt.Sprintf("%s/token_guard_max_failures", pathPrefix), 10),
+		TokenGuardBaseLockoutSeconds:   loader.GetInt(fmt.Sprintf("%s/token_guard_base_lockout_seconds", pathPrefix), 60),
+		TokenGuardMaxLockoutSeconds:    loader.GetInt(fmt.Sprintf("%s/token_guard_max_lockout_seconds", pathPrefix), 3600),
+		TokenGuardGlobalWindowSeconds:  loader.GetInt(fmt.Sprintf("%s/token_guard_global_window_seconds", pathPrefix), 60),
+		TokenGuardGlobalMaxFailures:    loader.GetInt(fmt.Sprintf("%s/token_guard_global_max_failures", pathPrefix), 1000),
+		TokenGuardGlobalLockoutSeconds: loader.GetInt(fmt.Sprintf("%s/token_guard_global_lockout_seconds", pathPrefix), 60),
+
+		ShortCodesEnabled:               shortCodesEnabled,
+		ShortCodeLength:                 loader.GetInt(fmt.Sprintf("%s/short_code_length", pathPrefix), 8),
+		ShortCodeGuardWindowSeconds:     loader.GetInt(fmt.Sprintf("%s/short_code_guard_window_seconds", pathPrefix), 3600),
+		ShortCodeGuardMaxFailures:       loader.GetInt(fmt.Sprintf("%s/short_code_guard_max_failures", pathPrefix), 5),
+		ShortCodeGuardGlobalMaxFailures: loader.GetInt(fmt.Sprintf("%s/short_code_guard_global_max_failures", pathPrefix), 100),
 	}
 }
@@ -0,0 +1,358 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// publishTimeout membatasi lama menunggu penerbitan dan konfirmasi broker.
+	publishTimeout = 5 * time.Second
+
+	// DefaultPublishBufferSize dan DefaultPublishBufferTimeout mengatur berapa banyak publish
+	// yang boleh menunggu koneksi pulih dan berapa lama masing-masing menunggu. Batas waktunya
+	// sengaja pendek karena publish berjalan di jalur request dan di chunk job; pengiriman ulang
+	// yang lebih lama diserahkan ke relay outbox.
+	DefaultPublishBufferSize    = 100
+	DefaultPublishBufferTimeout = 2 * time.Second
+
+	// Backoff eksponensial untuk percobaan koneksi ulang.
+	DefaultReconnectInitialBackoff = time.Second
+	DefaultReconnectMaxBackoff     = 30 * time.Second
+)
+
+// PublisherOption mengonfigurasi perilaku publisher RabbitMQ selama koneksi terputus.
+type PublisherOption func(*amqpChannel)
+
+// WithFailFast membuat publish langsung gagal dengan ErrPublisherDisconnected selama
+// koneksi ke broker terputus, alih-alih menunggu koneksi pulih.
+func WithFailFast() PublisherOption {
+	return func(a *amqpChannel) {
+		a.failFast = true
+	}
+}
+
+// WithPublishBuffer mengatur jumlah maksimum publish yang menunggu koneksi pulih dan
+// batas waktu tunggunya. Publish di luar kapasitas langsung gagal dengan ErrPublisherDisconnected.
+func WithPublishBuffer(size int, timeout time.Duration) PublisherOption {
+	return func(a *amqpChannel) {
+		a.buffer = make(chan struct{}, size)
+		a.bufferTimeout = timeout
+	}
+}
+
+// WithReconnectBackoff mengatur jeda awal dan maksimum percobaan koneksi ulang.
+func WithReconnectBackoff(initial, max time.Duration) PublisherOption {
+	return func(a *amqpChannel) {
+		a.initialBackoff = initial
+		a.maxBackoff = max
+	}
+}
+
+// amqpChannel membungkus koneksi dan channel RabbitMQ yang dipakai bersama oleh publisher.
+// Channel berjalan dalam mode confirm sehingga setiap publish menunggu ack atau nack broker.
+// Jika koneksi atau channel ditutup oleh broker, amqpChannel terhubung ulang dengan
+// backoff eksponensial dan mendeklarasikan ulang exchange-nya.
+type amqpChannel struct {
+	url       string
+	exchange  string
+	kind      string
+	mandatory bool
+
+	failFast       bool
+	buffer         chan struct{}
+	bufferTimeout  time.Duration
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+
+	stateMu  sync.Mutex
+	conn     *amqp091.Connection
+	channel  *amqp091.Channel
+	confirms *confirmTracker
+	// ready ditutup selama koneksi tersedia dan diganti saat koneksi terputus.
+	ready  chan struct{}
+	closed bool
+	done   chan struct{}
+
+	// connectFunc membuka koneksi ke broker; defaultnya connect.
+	connectFunc func() error
+}
+
+// dialExchange membuka koneksi dan channel baru dalam mode confirm, lalu memastikan
+// exchange yang dibutuhkan sudah ada. Koneksi awal harus berhasil; koneksi ulang
+// setelahnya ditangani di latar belakang.
+func dialExchange(amqpURL, exchange, kind string, mandatory bool, opts ...PublisherOption) (*amqpChannel, error) {
+	a := &amqpChannel{
+		url:            amqpURL,
+		exchange:       exchange,
+		kind:           kind,
+		mandatory:      mandatory,
+		buffer:         make(chan struct{}, DefaultPublishBufferSize),
+		bufferTimeout:  DefaultPublishBufferTimeout,
+		initialBackoff: DefaultReconnectInitialBackoff,
+		maxBackoff:     DefaultReconnectMaxBackoff,
+		ready:          make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	a.connectFunc = a.connect
+	for _, opt := range opts {
+		opt(a)
+	}
+	if err := a.connectFunc(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// connect membuka koneksi dan channel, mendeklarasikan exchange, mengaktifkan mode confirm
+// dan mulai mengawasi penutupan koneksi.
+func (a *amqpChannel) connect() error {
+	conn, err := amqp091.Dial(a.url)
+	if err != nil {
+		return fmt.Errorf("gagal terhubung ke RabbitMQ: %w", err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close() // Pastikan koneksi ditutup jika channel gagal dibuat.
+		return fmt.Errorf("gagal membuka channel RabbitMQ: %w", err)
+	}
+
+	// Pastikan exchange yang akan kita gunakan sudah ada.
+	// Ini membuat service lebih tangguh jika dijalankan sebelum infrastruktur RabbitMQ sepenuhnya siap.
+	err = ch.ExchangeDeclare(
+		a.exchange, // name
+		a.kind,     // type
+		true,       // durable
+		false,      // auto-deleted
+		false,      // internal
+		false,      // no-wait
+		nil,        // arguments
+	)
+	if err != nil {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("gagal mendeklarasikan exchange %s: %w", a.exchange, err)
+	}
+
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("gagal mengaktifkan publisher confirms: %w", err)
+	}
+
+	connClosed := conn.NotifyClose(make(chan *amqp091.Error, 1))
+	chClosed := ch.NotifyClose(make(chan *amqp091.Error, 1))
+	confirms := newConfirmTracker()
+	go confirms.run(ch.NotifyReturn(make(chan amqp091.Return, 1)), ch.NotifyPublish(make(chan amqp091.Confirmation, 1)))
+
+	a.stateMu.Lock()
+	if a.closed {
+		a.stateMu.Unlock()
+		ch.Close()
+		conn.Close()
+		return ErrPublisherClosed
+	}
+	a.conn, a.channel, a.confirms = conn, ch, confirms
+	close(a.ready)
+	a.stateMu.Unlock()
+
+	brokerConnected.WithLabelValues(a.exchange).Set(1)
+	go a.supervise(connClosed, chClosed)
+	return nil
+}
+
+// supervise menunggu koneksi atau channel ditutup, lalu memulai koneksi ulang.
+func (a *amqpChannel) supervise(connClosed, chClosed <-chan *amqp091.Error) {
+	var reason *amqp091.Error
+	select {
+	case reason = <-connClosed:
+	case reason = <-chClosed:
+	case <-a.done:
+		return
+	}
+
+	a.stateMu.Lock()
+	if a.closed {
+		a.stateMu.Unlock()
+		return
+	}
+	conn, ch := a.conn, a.channel
+	a.conn, a.channel, a.confirms = nil, nil, nil
+	a.ready = make(chan struct{})
+	a.stateMu.Unlock()
+
+	brokerConnected.WithLabelValues(a.exchange).Set(0)
+	log.Warn().Str("exchange", a.exchange).Interface("reason", reason).Msg("Koneksi RabbitMQ terputus, mencoba terhubung ulang")
+
+	// Channel dapat ditutup karena protocol error sementara koneksinya masih hidup;
+	// keduanya dibuka ulang agar state confirm dan listener selalu bersih.
+	_ = ch.Close()
+	_ = conn.Close()
+	a.reconnect()
+}
+
+// reconnect mencoba terhubung ulang dengan backoff eksponensial hingga berhasil atau publisher ditutup.
+func (a *amqpChannel) reconnect() {
+	backoff := a.initialBackoff
+	for attempt := 1; ; attempt++ {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-a.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		err := a.connectFunc()
+		if err == nil {
+			brokerReconnects.WithLabelValues(a.exchange).Inc()
+			log.Info().Str("exchange", a.exchange).Int("attempt", attempt).Msg("Koneksi RabbitMQ pulih")
+			return
+		}
+		if errors.Is(err, ErrPublisherClosed) {
+			return
+		}
+		log.Warn().Err(err).Str("exchange", a.exchange).Int("attempt", attempt).Dur("backoff", backoff).Msg("Gagal terhubung ulang ke RabbitMQ")
+
+		backoff *= 2
+		if backoff > a.maxBackoff {
+			backoff = a.maxBackoff
+		}
+	}
+}
+
+// awaitChannel mengembalikan channel yang aktif. Selama koneksi terputus, pemanggil langsung
+// gagal (mode fail-fast) atau menunggu koneksi pulih selama kapasitas buffer masih tersedia.
+func (a *amqpChannel) awaitChannel(ctx context.Context) (*amqp091.Channel, *confirmTracker, error) {
+	waiting := false
+	var timeout <-chan time.Time
+	for {
+		a.stateMu.Lock()
+		ch, confirms, ready, closed := a.channel, a.confirms, a.ready, a.closed
+		a.stateMu.Unlock()
+
+		if closed {
+			return nil, nil, ErrPublisherClosed
+		}
+		if ch != nil {
+			return ch, confirms, nil
+		}
+		if a.failFast {
+			return nil, nil, ErrPublisherDisconnected
+		}
+		if !waiting {
+			select {
+			case a.buffer <- struct{}{}:
+				defer func() { <-a.buffer }()
+			default:
+				return nil, nil, fmt.Errorf("%w: buffer publish penuh", ErrPublisherDisconnected)
+			}
+			timer := time.NewTimer(a.bufferTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+			waiting = true
+		}
+
+		select {
+		case <-ready:
+		case <-a.done:
+			return nil, nil, ErrPublisherClosed
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf("%w: %w", ErrPublisherDisconnected, ctx.Err())
+		case <-timeout:
+			return nil, nil, fmt.Errorf("%w: koneksi tidak pulih dalam %s", ErrPublisherDisconnected, a.bufferTimeout)
+		}
+	}
+}
+
+// publish menerbitkan satu pesan dan menunggu konfirmasi broker dengan batas waktu agar
+// pemanggil tidak terblokir terlalu lama. Pesan tanpa MessageId diberi ID acak agar
+// basic.return dapat dikaitkan dengan pesannya.
+func (a *amqpChannel) publish(ctx context.Context, exchange, routingKey string, msg amqp091.Publishing) error {
+	err := a.publishConfirmed(ctx, exchange, routingKey, msg)
+	recordPublish(exchange, err)
+	return err
+}
+
+func (a *amqpChannel) publishConfirmed(ctx context.Context, exchange, routingKey string, msg amqp091.Publishing) error {
+	ch, confirms, err := a.awaitChannel(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+
+	if msg.MessageId == "" {
+		msg.MessageId = uuid.NewString()
+	}
+
+	pending, err := confirms.publish(msg.MessageId, func() (*amqp091.DeferredConfirmation, error) {
+		return ch.PublishWithDeferredConfirmWithContext(
+			ctx,
+			exchange,    // exchange
+			routingKey,  // routing key
+			a.mandatory, // mandatory
+			false,       // immediate
+			msg,
+		)
+	})
+	if err != nil {
+		return fmt.Errorf("gagal menerbitkan pesan: %w", err)
+	}
+
+	var result confirmResult
+	select {
+	case result = <-pending.done:
+	case <-ctx.Done():
+		confirms.forget(pending)
+		return fmt.Errorf("konfirmasi broker tidak diterima: %w", ctx.Err())
+	}
+	if result.err != nil {
+		return fmt.Errorf("konfirmasi broker tidak diterima: %w", result.err)
+	}
+	if !result.acked {
+		return ErrPublishNacked
+	}
+	if ret := result.returned; ret != nil {
+		return &ReturnedError{Exchange: ret.Exchange, RoutingKey: ret.RoutingKey, ReplyCode: ret.ReplyCode, ReplyText: ret.ReplyText}
+	}
+	return nil
+}
+
+// Close menghentikan koneksi ulang serta menutup channel dan koneksi RabbitMQ.
+func (a *amqpChannel) Close() error {
+	a.stateMu.Lock()
+	if a.closed {
+		a.stateMu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.done)
+	conn, ch := a.conn, a.channel
+	a.conn, a.channel, a.confirms = nil, nil, nil
+	a.stateMu.Unlock()
+
+	var firstErr error
+	if ch != nil {
+		if err := ch.Close(); err != nil {
+			firstErr = fmt.Errorf("gagal menutup channel: %w", err)
+		}
+	}
+	if conn != nil {
+		if err := conn.Close(); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("gagal menutup koneksi: %w", err)
+			}
+		}
+	}
+	return firstErr
+}
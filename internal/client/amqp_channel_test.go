@@ -0,0 +1,232 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trackedPublish mendaftarkan publish dengan delivery tag tertentu tanpa broker.
+func trackedPublish(t *testing.T, tracker *confirmTracker, messageID string, tag uint64) *pendingConfirm {
+	t.Helper()
+	pending, err := tracker.publish(messageID, func() (*amqp091.DeferredConfirmation, error) {
+		return &amqp091.DeferredConfirmation{DeliveryTag: tag}, nil
+	})
+	require.NoError(t, err)
+	return pending
+}
+
+func awaitResult(t *testing.T, pending *pendingConfirm) confirmResult {
+	t.Helper()
+	select {
+	case result := <-pending.done:
+		return result
+	case <-time.After(time.Second):
+		t.Fatal("konfirmasi tidak diselesaikan")
+		return confirmResult{}
+	}
+}
+
+func TestConfirmTracker(t *testing.T) {
+	t.Run("Ack dan nack dikaitkan dengan delivery tag", func(t *testing.T) {
+		tracker := newConfirmTracker()
+		returns := make(chan amqp091.Return)
+		confirms := make(chan amqp091.Confirmation)
+		go tracker.run(returns, confirms)
+		defer close(confirms)
+
+		first := trackedPublish(t, tracker, "msg-1", 1)
+		second := trackedPublish(t, tracker, "msg-2", 2)
+
+		confirms <- amqp091.Confirmation{DeliveryTag: 2, Ack: false}
+		confirms <- amqp091.Confirmation{DeliveryTag: 1, Ack: true}
+
+		assert.False(t, awaitResult(t, second).acked)
+		assert.True(t, awaitResult(t, first).acked)
+	})
+
+	t.Run("Return yang tiba bersamaan dengan ack tidak terlewat", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			tracker := newConfirmTracker()
+			returns := make(chan amqp091.Return, 1)
+			confirms := make(chan amqp091.Confirmation, 1)
+			pending := trackedPublish(t, tracker, "msg-1", 1)
+
+			// Keduanya sudah siap dibaca sehingga select dapat memilih ack lebih dulu.
+			returns <- amqp091.Return{MessageId: "msg-1", Exchange: ExchangeName, RoutingKey: RoutingKey, ReplyCode: 312, ReplyText: "NO_ROUTE"}
+			confirms <- amqp091.Confirmation{DeliveryTag: 1, Ack: true}
+			go tracker.run(returns, confirms)
+
+			result := awaitResult(t, pending)
+			assert.True(t, result.acked)
+			require.NotNil(t, result.returned)
+			assert.Equal(t, uint16(312), result.returned.ReplyCode)
+			close(confirms)
+		}
+	})
+
+	t.Run("Publish yang menunggu gagal saat channel ditutup", func(t *testing.T) {
+		tracker := newConfirmTracker()
+		returns := make(chan amqp091.Return)
+		confirms := make(chan amqp091.Confirmation)
+		pending := trackedPublish(t, tracker, "msg-1", 1)
+
+		go tracker.run(returns, confirms)
+		close(returns)
+		close(confirms)
+
+		result := awaitResult(t, pending)
+		assert.ErrorIs(t, result.err, ErrPublisherDisconnected)
+
+		_, err := tracker.publish("msg-2", func() (*amqp091.DeferredConfirmation, error) {
+			t.Fatal("pesan tidak boleh diterbitkan ke channel yang sudah ditutup")
+			return nil, nil
+		})
+		assert.ErrorIs(t, err, ErrPublisherDisconnected)
+	})
+}
+
+func TestAmqpChannel_Reconnect(t *testing.T) {
+	newDisconnected := func(connect func(a *amqpChannel) error) *amqpChannel {
+		a := &amqpChannel{
+			exchange:       "test_exchange",
+			buffer:         make(chan struct{}, 1),
+			bufferTimeout:  time.Second,
+			initialBackoff: time.Millisecond,
+			maxBackoff:     5 * time.Millisecond,
+			ready:          make(chan struct{}),
+			done:           make(chan struct{}),
+		}
+		a.connectFunc = func() error { return connect(a) }
+		return a
+	}
+
+	t.Run("Publish menunggu koneksi pulih setelah beberapa percobaan", func(t *testing.T) {
+		attempts := 0
+		a := newDisconnected(func(a *amqpChannel) error {
+			if attempts++; attempts < 3 {
+				return errors.New("connection refused")
+			}
+			a.stateMu.Lock()
+			defer a.stateMu.Unlock()
+			a.channel, a.confirms = &amqp091.Channel{}, newConfirmTracker()
+			close(a.ready)
+			return nil
+		})
+		go a.reconnect()
+
+		ch, confirms, err := a.awaitChannel(context.Background())
+		require.NoError(t, err)
+		assert.NotNil(t, ch)
+		assert.NotNil(t, confirms)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("Mode fail-fast tidak menunggu koneksi pulih", func(t *testing.T) {
+		a := newDisconnected(func(*amqpChannel) error { return errors.New("connection refused") })
+		a.failFast = true
+
+		_, _, err := a.awaitChannel(context.Background())
+		assert.ErrorIs(t, err, ErrPublisherDisconnected)
+	})
+
+	t.Run("Koneksi ulang berhenti saat publisher ditutup", func(t *testing.T) {
+		a := newDisconnected(func(*amqpChannel) error { return errors.New("connection refused") })
+		stopped := make(chan struct{})
+		go func() {
+			a.reconnect()
+			close(stopped)
+		}()
+
+		require.NoError(t, a.Close())
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("koneksi ulang tidak berhenti")
+		}
+		_, _, err := a.awaitChannel(context.Background())
+		assert.ErrorIs(t, err, ErrPublisherClosed)
+	})
+}
+
+func TestAmqpChannel_PublishBuffer(t *testing.T) {
+	newBuffered := func(size int, timeout time.Duration) *amqpChannel {
+		a := &amqpChannel{
+			exchange: "test_exchange",
+			ready:    make(chan struct{}),
+			done:     make(chan struct{}),
+		}
+		WithPublishBuffer(size, timeout)(a)
+		return a
+	}
+
+	t.Run("Publish yang menunggu berhasil saat koneksi pulih", func(t *testing.T) {
+		a := newBuffered(1, time.Second)
+		result := make(chan error, 1)
+		go func() {
+			_, _, err := a.awaitChannel(context.Background())
+			result <- err
+		}()
+		require.Eventually(t, func() bool { return len(a.buffer) == 1 }, time.Second, time.Millisecond)
+
+		a.stateMu.Lock()
+		a.channel, a.confirms = &amqp091.Channel{}, newConfirmTracker()
+		close(a.ready)
+		a.stateMu.Unlock()
+
+		select {
+		case err := <-result:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("publish tidak dilanjutkan setelah koneksi pulih")
+		}
+		assert.Empty(t, a.buffer, "slot buffer harus dilepas setelah publish dilanjutkan")
+	})
+
+	t.Run("Publish gagal setelah batas waktu buffer", func(t *testing.T) {
+		a := newBuffered(1, 20*time.Millisecond)
+
+		start := time.Now()
+		_, _, err := a.awaitChannel(context.Background())
+		assert.ErrorIs(t, err, ErrPublisherDisconnected)
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+		assert.Empty(t, a.buffer)
+	})
+
+	t.Run("Publish langsung gagal saat buffer penuh", func(t *testing.T) {
+		a := newBuffered(1, time.Second)
+		ctx, cancel := context.WithCancel(context.Background())
+		waiting := make(chan error, 1)
+		go func() {
+			_, _, err := a.awaitChannel(ctx)
+			waiting <- err
+		}()
+		require.Eventually(t, func() bool { return len(a.buffer) == 1 }, time.Second, time.Millisecond)
+
+		start := time.Now()
+		_, _, err := a.awaitChannel(context.Background())
+		assert.ErrorIs(t, err, ErrPublisherDisconnected)
+		assert.Less(t, time.Since(start), 500*time.Millisecond, "publish di luar kapasitas tidak boleh menunggu")
+
+		cancel()
+		assert.ErrorIs(t, <-waiting, context.Canceled)
+	})
+
+	t.Run("Publish yang menunggu gagal saat publisher ditutup", func(t *testing.T) {
+		a := newBuffered(1, time.Second)
+		result := make(chan error, 1)
+		go func() {
+			_, _, err := a.awaitChannel(context.Background())
+			result <- err
+		}()
+		require.Eventually(t, func() bool { return len(a.buffer) == 1 }, time.Second, time.Millisecond)
+
+		require.NoError(t, a.Close())
+		assert.ErrorIs(t, <-result, ErrPublisherClosed)
+	})
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog/log"
+)
+
+// confirmTracker mengaitkan konfirmasi dan basic.return dari satu channel dengan publish yang
+// menunggunya berdasarkan delivery tag, sehingga beberapa publish dapat menunggu konfirmasi
+// bersamaan. Delivery tag dimulai ulang untuk setiap channel, sehingga setiap koneksi memiliki
+// tracker sendiri.
+type confirmTracker struct {
+	mu        sync.Mutex
+	pending   map[uint64]*pendingConfirm
+	byMessage map[string]*pendingConfirm
+	closed    bool
+}
+
+// pendingConfirm adalah publish yang sedang menunggu konfirmasi broker.
+type pendingConfirm struct {
+	tag       uint64
+	messageID string
+	returned  *amqp091.Return
+	done      chan confirmResult
+}
+
+// confirmResult adalah hasil akhir publish: ack atau nack broker, basic.return jika pesan
+// mandatory tidak dapat dirutekan, atau err jika channel ditutup sebelum konfirmasi diterima.
+type confirmResult struct {
+	acked    bool
+	returned *amqp091.Return
+	err      error
+}
+
+func newConfirmTracker() *confirmTracker {
+	return &confirmTracker{
+		pending:   make(map[uint64]*pendingConfirm),
+		byMessage: make(map[string]*pendingConfirm),
+	}
+}
+
+// publish menerbitkan pesan melalui send dan mendaftarkannya dengan delivery tag dari channel.
+// send dipanggil sambil memegang lock agar konfirmasinya tidak diproses sebelum terdaftar.
+func (t *confirmTracker) publish(messageID string, send func() (*amqp091.DeferredConfirmation, error)) (*pendingConfirm, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, ErrPublisherDisconnected
+	}
+	confirmation, err := send()
+	if err != nil {
+		return nil, err
+	}
+	p := &pendingConfirm{tag: confirmation.DeliveryTag, messageID: messageID, done: make(chan confirmResult, 1)}
+	t.pending[p.tag] = p
+	t.byMessage[messageID] = p
+	return p, nil
+}
+
+// forget berhenti melacak publish yang tidak lagi menunggu, misalnya karena batas waktunya habis.
+func (t *confirmTracker) forget(p *pendingConfirm) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.remove(p)
+}
+
+func (t *confirmTracker) remove(p *pendingConfirm) {
+	if t.pending[p.tag] == p {
+		delete(t.pending, p.tag)
+	}
+	if t.byMessage[p.messageID] == p {
+		delete(t.byMessage, p.messageID)
+	}
+}
+
+// run memproses basic.return dan konfirmasi dalam satu loop hingga channel ditutup. Broker
+// mengirim basic.return sebelum ack untuk pesan yang sama dan amqp091 menyerahkannya ke returns
+// sebelum memproses ack tersebut, tetapi select dapat memilih konfirmasi lebih dulu. Karena itu
+// returns selalu dikuras sebelum konfirmasi diselesaikan.
+func (t *confirmTracker) run(returns <-chan amqp091.Return, confirms <-chan amqp091.Confirmation) {
+	defer t.failAll(fmt.Errorf("%w: channel ditutup sebelum konfirmasi diterima", ErrPublisherDisconnected))
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			t.returned(ret)
+		case confirmation, ok := <-confirms:
+			if !ok {
+				return
+			}
+			returns = t.drainReturns(returns)
+			t.confirm(confirmation)
+		}
+	}
+}
+
+// drainReturns mencatat semua basic.return yang sudah diterima tanpa menunggu yang baru.
+func (t *confirmTracker) drainReturns(returns <-chan amqp091.Return) <-chan amqp091.Return {
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				return nil
+			}
+			t.returned(ret)
+		default:
+			return returns
+		}
+	}
+}
+
+func (t *confirmTracker) returned(ret amqp091.Return) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if p, ok := t.byMessage[ret.MessageId]; ok && ret.MessageId != "" {
+		p.returned = &ret
+		return
+	}
+	log.Warn().Str("message_id", ret.MessageId).Str("exchange", ret.Exchange).Str("routing_key", ret.RoutingKey).Msg("Menerima basic.return untuk pesan yang tidak lagi ditunggu")
+}
+
+func (t *confirmTracker) confirm(confirmation amqp091.Confirmation) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p, ok := t.pending[confirmation.DeliveryTag]
+	if !ok {
+		return
+	}
+	t.remove(p)
+	p.done <- confirmResult{acked: confirmation.Ack, returned: p.returned}
+}
+
+// failAll menggagalkan semua publish yang masih menunggu ketika channel ditutup.
+func (t *confirmTracker) failAll(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	for _, p := range t.pending {
+		t.remove(p)
+		p.done <- confirmResult{err: err}
+	}
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPublishNacked dikembalikan jika broker menolak pesan (basic.nack) sehingga pesan tidak tersimpan.
+var ErrPublishNacked = errors.New("pesan ditolak oleh broker RabbitMQ")
+
+// ErrPublishUnroutable dikembalikan jika pesan mandatory tidak dapat dirutekan ke antrean mana pun.
+var ErrPublishUnroutable = errors.New("pesan tidak dapat dirutekan ke antrean mana pun")
+
+// ErrPublisherDisconnected dikembalikan jika koneksi ke broker sedang terputus dan pesan
+// tidak dapat menunggu koneksi pulih (mode fail-fast, buffer penuh atau batas waktu habis).
+var ErrPublisherDisconnected = errors.New("koneksi RabbitMQ sedang terputus")
+
+// ErrPublisherClosed dikembalikan jika publish dipanggil setelah publisher ditutup.
+var ErrPublisherClosed = errors.New("publisher RabbitMQ sudah ditutup")
+
+// ReturnedError berisi detail basic.return dari broker untuk pesan mandatory yang tidak
+// terkirim, misalnya karena binding antrean belum dibuat. Gunakan errors.Is dengan
+// ErrPublishUnroutable untuk memeriksanya.
+type ReturnedError struct {
+	Exchange   string
+	RoutingKey string
+	ReplyCode  uint16
+	ReplyText  string
+}
+
+func (e *ReturnedError) Error() string {
+	return fmt.Sprintf("pesan dikembalikan broker (exchange=%s, routing_key=%s): %d %s", e.Exchange, e.RoutingKey, e.ReplyCode, e.ReplyText)
+}
+
+func (e *ReturnedError) Unwrap() error {
+	return ErrPublishUnroutable
+}
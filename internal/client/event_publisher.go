@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog/log"
+)
+
+// EventsExchangeName adalah topic exchange untuk domain event siklus hidup undangan.
+// Routing key setiap pesan sama dengan tipe event, sehingga konsumen dapat
+// berlangganan dengan pola seperti "invitation.*" atau "invitation.accepted".
+const EventsExchangeName = "prism_invitation_events_exchange"
+
+// EventSchemaVersion adalah versi envelope DomainEvent. Naikkan nilainya jika
+// terjadi perubahan yang tidak kompatibel pada envelope maupun payload.
+const EventSchemaVersion = 1
+
+// Tipe domain event yang diterbitkan oleh invitation-service.
+const (
+	EventInvitationCreated  = "invitation.created"
+	EventInvitationAccepted = "invitation.accepted"
+	EventInvitationRevoked  = "invitation.revoked"
+	EventInvitationExpired  = "invitation.expired"
+	EventInvitationResent   = "invitation.resent"
+	EventInvitationUpdated  = "invitation.updated"
+)
+
+// DomainEvent adalah envelope berversi untuk semua event yang diterbitkan ke EventsExchangeName.
+type DomainEvent struct {
+	ID         string      `json:"event_id"`
+	Type       string      `json:"event_type"`
+	Version    int         `json:"version"`
+	TenantID   string      `json:"tenant_id"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Payload    interface{} `json:"payload"`
+}
+
+// EventPublisher menerbitkan domain event ke service Prism lain (audit, user, analytics).
+type EventPublisher interface {
+	Publish(ctx context.Context, event DomainEvent) error
+	Close() error
+}
+
+// rabbitMQEventPublisher adalah implementasi EventPublisher di atas RabbitMQ.
+type rabbitMQEventPublisher struct {
+	*amqpChannel
+}
+
+// NewEventPublisher membuat publisher domain event dengan koneksi RabbitMQ tersendiri,
+// terpisah dari koneksi yang dipakai untuk notifikasi email. Event tidak diterbitkan
+// sebagai mandatory karena topic exchange boleh belum memiliki pelanggan.
+func NewEventPublisher(amqpURL string, opts ...PublisherOption) (EventPublisher, error) {
+	ch, err := dialExchange(amqpURL, EventsExchangeName, amqp091.ExchangeTopic, false, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &rabbitMQEventPublisher{amqpChannel: ch}, nil
+}
+
+// Publish menerbitkan domain event dengan tipe event sebagai routing key.
+func (p *rabbitMQEventPublisher) Publish(ctx context.Context, event DomainEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("gagal marshal domain event: %w", err)
+	}
+
+	log.Debug().Str("event_type", event.Type).Str("event_id", event.ID).Msg("Menerbitkan domain event ke RabbitMQ")
+
+	return p.publish(ctx, EventsExchangeName, event.Type, amqp091.Publishing{
+		ContentType:  ContentTypeJSON,
+		DeliveryMode: amqp091.Persistent,
+		MessageId:    event.ID,
+		Type:         event.Type,
+		Timestamp:    event.OccurredAt,
+		Body:         body,
+	})
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Hasil penerbitan pesan yang dicatat pada label "result".
+const (
+	publishResultAcked        = "acked"
+	publishResultNacked       = "nacked"
+	publishResultReturned     = "returned"
+	publishResultDisconnected = "disconnected"
+	publishResultFailed       = "failed"
+)
+
+// publishTotal menghitung pesan yang diterbitkan ke RabbitMQ per exchange dan hasil konfirmasi broker.
+var publishTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "prism_invitation_broker_publish_total",
+	Help: "Jumlah pesan yang diterbitkan ke RabbitMQ berdasarkan exchange dan hasil konfirmasi broker.",
+}, []string{"exchange", "result"})
+
+// brokerConnected bernilai 1 selama publisher terhubung ke broker dan 0 selama terputus.
+var brokerConnected = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "prism_invitation_broker_connected",
+	Help: "Status koneksi publisher RabbitMQ per exchange (1 terhubung, 0 terputus).",
+}, []string{"exchange"})
+
+// brokerReconnects menghitung koneksi ulang yang berhasil setelah koneksi ke broker terputus.
+var brokerReconnects = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "prism_invitation_broker_reconnects_total",
+	Help: "Jumlah koneksi ulang publisher RabbitMQ yang berhasil per exchange.",
+}, []string{"exchange"})
+
+// recordPublish mencatat hasil penerbitan berdasarkan error yang dikembalikan publish.
+func recordPublish(exchange string, err error) {
+	result := publishResultAcked
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrPublishNacked):
+		result = publishResultNacked
+	case errors.Is(err, ErrPublishUnroutable):
+		result = publishResultReturned
+	case errors.Is(err, ErrPublisherDisconnected), errors.Is(err, ErrPublisherClosed):
+		result = publishResultDisconnected
+	default:
+		result = publishResultFailed
+	}
+	publishTotal.WithLabelValues(exchange, result).Inc()
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	commonauth "github.com/Lumina-Enterprise-Solutions/prism-common-libs/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// Izin yang dibutuhkan route undangan yang diakses oleh pengguna tenant.
+const (
+	PermissionInvitationsCreate = "invitations:create"
+	PermissionInvitationsRead   = "invitations:read"
+	PermissionInvitationsRevoke = "invitations:revoke"
+	PermissionInvitationsResend = "invitations:resend"
+)
+
+// DefaultPermissionsClaim adalah klaim JWT yang dibaca ClaimsAuthorizer jika tidak ditentukan.
+const DefaultPermissionsClaim = "permissions"
+
+// Authorizer memutuskan apakah pemanggil yang sudah terautentikasi memiliki izin tertentu.
+// Error dikembalikan jika keputusan tidak dapat dibuat, misalnya karena sumber kebijakan
+// tidak tersedia.
+type Authorizer interface {
+	Authorize(c *gin.Context, permission string) (bool, error)
+}
+
+// AuthorizerFunc memungkinkan fungsi kebijakan biasa dipakai sebagai Authorizer.
+type AuthorizerFunc func(c *gin.Context, permission string) (bool, error)
+
+func (f AuthorizerFunc) Authorize(c *gin.Context, permission string) (bool, error) {
+	return f(c, permission)
+}
+
+// PermissionMiddleware membuat middleware yang menolak pemanggil tanpa izin permission, misalnya
+// (*commonauth.RBACMiddleware).RequirePermission.
+type PermissionMiddleware func(permission string) gin.HandlerFunc
+
+// WithPermissionMiddleware memakai middleware izin bersama, seperti commonauth.RBACMiddleware
+// yang memetakan role pemanggil ke izin melalui user-service, menggantikan Authorizer.
+func WithPermissionMiddleware(middleware PermissionMiddleware) HandlerOption {
+	return func(h *InvitationHandler) {
+		h.permission = middleware
+	}
+}
+
+// ClaimsAuthorizer membaca daftar izin pemanggil dari klaim JWT. Klaim boleh berupa array
+// string atau string yang dipisah spasi. Authorizer ini dipakai jika user-service tidak
+// dikonfigurasi: commonauth.RBACMiddleware hanya membaca klaim role dan mengambil izinnya
+// melalui gRPC, sehingga tidak dapat memakai izin yang sudah disematkan penerbit token.
+type ClaimsAuthorizer struct {
+	claim string
+}
+
+// NewClaimsAuthorizer membuat authorizer yang membaca izin dari klaim dengan nama claim.
+func NewClaimsAuthorizer(claim string) *ClaimsAuthorizer {
+	if claim == "" {
+		claim = DefaultPermissionsClaim
+	}
+	return &ClaimsAuthorizer{claim: claim}
+}
+
+func (a *ClaimsAuthorizer) Authorize(c *gin.Context, permission string) (bool, error) {
+	claims, _ := c.Get(commonauth.ClaimsKey)
+	mapClaims, _ := claims.(jwt.MapClaims)
+	switch granted := mapClaims[a.claim].(type) {
+	case string:
+		for _, p := range strings.Fields(granted) {
+			if p == permission {
+				return true, nil
+			}
+		}
+	case []interface{}:
+		for _, p := range granted {
+			if p == permission {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// WithAuthorizer mengaktifkan pemeriksaan izin pada route yang terdaftar melalui RegisterRoutes.
+func WithAuthorizer(authorizer Authorizer) HandlerOption {
+	return func(h *InvitationHandler) {
+		h.authorizer = authorizer
+	}
+}
+
+// route adalah satu entri tabel kebijakan route undangan. Permission kosong menandai route
+// publik yang diamankan oleh token undangan, bukan oleh JWT.
+type route struct {
+	method     string
+	path       string
+	permission string
+	handle     gin.HandlerFunc
+}
+
+// routes adalah tabel kebijakan seluruh route di bawah grup /invitations.
+func (h *InvitationHandler) routes() []route {
+	return []route{
+		{http.MethodPost, "", PermissionInvitationsCreate, h.CreateInvitation},
+		{http.MethodPost, "/bulk", PermissionInvitationsCreate, h.BulkCreateInvitations},
+		{http.MethodPost, "/import", PermissionInvitationsCreate, h.ImportInvitations},
+		{http.MethodGet, "/jobs/:id", PermissionInvitationsCreate, h.GetJob},
+		{http.MethodGet, "", PermissionInvitationsRead, h.ListInvitations},
+		{http.MethodDelete, "/:id", PermissionInvitationsRevoke, h.RevokeInvitation},
+		{http.MethodPost, "/:id/resend", PermissionInvitationsResend, h.ResendInvitation},
+		{http.MethodPost, "/validate", "", h.ValidateInvitation},
+		{http.MethodGet, "/preview", "", h.PreviewInvitation},
+		{http.MethodPost, "/accept", "", h.ValidateInvitation},
+		{http.MethodPost, "/reserve", "", h.ReserveInvitation},
+		{http.MethodPost, "/commit", "", h.CommitInvitation},
+		{http.MethodPost, "/release", "", h.ReleaseInvitation},
+	}
+}
+
+// RegisterRoutes mendaftarkan seluruh route undangan ke group. Route yang membutuhkan izin
+// dijalankan di belakang authenticate (misalnya JWTMiddleware) dan PermissionMiddleware, atau
+// pemeriksaan Authorizer jika PermissionMiddleware tidak dikonfigurasi.
+func (h *InvitationHandler) RegisterRoutes(group *gin.RouterGroup, authenticate gin.HandlerFunc) {
+	for _, r := range h.routes() {
+		handlers := []gin.HandlerFunc{r.handle}
+		if r.permission != "" {
+			check := h.requirePermission
+			if h.permission != nil {
+				check = h.permission
+			}
+			handlers = []gin.HandlerFunc{authenticate, check(r.permission), r.handle}
+		}
+		group.Handle(r.method, r.path, handlers...)
+	}
+}
+
+// requirePermission menolak permintaan dengan 403 jika Authorizer tidak memberikan izin.
+// Tanpa Authorizer, setiap pemanggil yang terautentikasi diizinkan.
+func (h *InvitationHandler) requirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.authorizer == nil {
+			c.Next()
+			return
+		}
+		allowed, err := h.authorizer.Authorize(c, permission)
+		if err != nil {
+			log.Error().Err(err).Str("permission", permission).Msg("Gagal memeriksa izin pemanggil")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "gagal memeriksa izin"})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("akses ditolak: izin '%s' diperlukan", permission)})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// MaxBulkInvitations membatasi jumlah undangan dalam satu permintaan massal.
+const MaxBulkInvitations = 500
+
+type bulkCreateRequest struct {
+	Invitations []createInvitationRequest `json:"invitations" binding:"required,min=1"`
+}
+
+// rowResult adalah hasil pemrosesan satu baris undangan massal.
+type rowResult struct {
+	Index     int        `json:"index"`
+	Email     string     `json:"email"`
+	Status    string     `json:"status"`
+	ID        string     `json:"id,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// bulkResponse berisi hasil setiap baris beserta ringkasan jumlah per status.
+type bulkResponse struct {
+	Summary map[string]int `json:"summary"`
+	Results []rowResult    `json:"results"`
+}
+
+// BulkCreateInvitations membuat banyak undangan dalam satu permintaan. Setiap baris divalidasi
+// dengan aturan yang sama seperti CreateInvitation dan diduplikasi berdasarkan email;
+// kegagalan satu baris tidak membatalkan baris lainnya. Dengan ?async=true, baris diproses
+// oleh worker job dan respons 202 berisi ID job untuk dipantau melalui GetJob.
+func (h *InvitationHandler) BulkCreateInvitations(c *gin.Context) {
+	var req bulkCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Invitations) > MaxBulkInvitations {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "jumlah undangan melebihi batas per permintaan"})
+		return
+	}
+
+	tenantID, inviterID, ok := inviterFromContext(c)
+	if !ok || !h.checkAsync(c) {
+		return
+	}
+
+	checker := newRowChecker()
+	results := make([]rowResult, len(req.Invitations))
+	pending := make([]pendingRow, 0, len(req.Invitations))
+	for i, row := range req.Invitations {
+		results[i] = checker.check(i, &row)
+		if results[i].Status == "" {
+			pending = append(pending, pendingRow{req: row, result: i})
+		}
+	}
+
+	if isAsync(c) {
+		job, ok := h.createJob(c, tenantID, inviterID, jobSourceBulk)
+		if !ok {
+			return
+		}
+		if err := h.appendJobRows(c, job.ID, tenantID, inviterID, pending, results); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal menyimpan baris undangan ke job"})
+			return
+		}
+		h.startJob(c, job)
+		return
+	}
+
+	h.createRows(c, tenantID, inviterID, pending, results)
+	c.JSON(http.StatusOK, newBulkResponse(results))
+}
+
+// rowChecker menerapkan aturan validasi CreateInvitation dan deteksi email ganda pada
+// setiap baris masukan massal, baik dari JSON maupun CSV.
+type rowChecker struct {
+	seen map[string]bool
+}
+
+func newRowChecker() *rowChecker {
+	return &rowChecker{seen: make(map[string]bool)}
+}
+
+// check memvalidasi satu baris. Status hasil kosong jika baris siap dibuat.
+func (rc *rowChecker) check(index int, row *createInvitationRequest) rowResult {
+	// Spasi di sekitar email sering terbawa dari hasil salin-tempel daftar karyawan.
+	row.Email = strings.TrimSpace(row.Email)
+	result := rowResult{Index: index, Email: row.Email}
+	if err := binding.Validator.ValidateStruct(row); err != nil {
+		result.Status = service.RowStatusInvalid
+		result.Error = err.Error()
+		return result
+	}
+	email := service.NormalizeEmail(row.Email)
+	if rc.seen[email] {
+		result.Status = service.RowStatusDuplicate
+		result.Error = "email sudah ada di baris sebelumnya"
+		return result
+	}
+	rc.seen[email] = true
+	return result
+}
+
+// pendingRow adalah baris valid yang menunggu dibuat, beserta posisi hasilnya.
+type pendingRow struct {
+	req    createInvitationRequest
+	result int
+}
+
+// createRows membuat undangan untuk baris yang valid dan mengisi hasilnya ke results.
+func (h *InvitationHandler) createRows(c *gin.Context, tenantID, inviterID string, rows []pendingRow, results []rowResult) {
+	if len(rows) == 0 {
+		return
+	}
+	reqs := make([]service.CreateInvitationRequest, len(rows))
+	for i, row := range rows {
+		reqs[i] = newCreateRequest(c, row.req, tenantID, inviterID)
+	}
+	for i, created := range h.service.CreateInvitations(c.Request.Context(), reqs) {
+		applyCreateResult(&results[rows[i].result], created)
+	}
+}
+
+// applyCreateResult mengisi hasil baris berdasarkan hasil pembuatan undangan di service.
+func applyCreateResult(row *rowResult, created service.BulkCreateResult) {
+	var dup *service.DuplicateInvitationError
+	if errors.As(created.Err, &dup) {
+		// ID undangan yang sudah ada dikembalikan agar pemanggil dapat mengirimnya ulang.
+		row.Status = service.RowStatusDuplicate
+		row.ID = dup.InvitationID
+		row.Error = dup.Error()
+		return
+	}
+	if errors.Is(created.Err, service.ErrUnknownRole) || errors.Is(created.Err, service.ErrRoleNotGrantable) ||
+		errors.Is(created.Err, service.ErrShortCodesDisabled) || errors.Is(created.Err, service.ErrExpiryInPast) {
+		row.Status = service.RowStatusInvalid
+		row.Error = created.Err.Error()
+		return
+	}
+	if errors.Is(created.Err, service.ErrRateLimited) || errors.Is(created.Err, service.ErrSeatLimitExceeded) {
+		row.Status = service.RowStatusFailed
+		row.Error = created.Err.Error()
+		return
+	}
+	if created.Err != nil {
+		row.Status = service.RowStatusFailed
+		row.Error = "gagal membuat undangan"
+		return
+	}
+	row.Status = service.RowStatusCreated
+	row.ID = created.Invitation.ID
+	row.ExpiresAt = &created.Invitation.ExpiresAt
+}
+
+func newBulkResponse(results []rowResult) bulkResponse {
+	summary := map[string]int{service.RowStatusCreated: 0, service.RowStatusDuplicate: 0, service.RowStatusInvalid: 0, service.RowStatusFailed: 0}
+	for _, row := range results {
+		summary[row.Status]++
+	}
+	return bulkResponse{Summary: summary, Results: results}
+}
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// MaxImportRows membatasi jumlah baris data dalam satu berkas impor CSV.
+	MaxImportRows = 10000
+	// MaxImportFileSize membatasi ukuran unggahan impor CSV.
+	MaxImportFileSize = 10 << 20
+
+	// importFormField adalah nama field multipart yang berisi berkas CSV.
+	importFormField = "file"
+	// importBatchSize adalah jumlah baris yang dibaca sebelum undangan dibuat dan laporan ditulis.
+	importBatchSize = 100
+)
+
+var errImportFileMissing = errors.New("berkas CSV wajib diunggah pada field 'file'")
+
+// importColumns memetakan nama kolom CSV ke posisinya. Kolom email dan role wajib ada,
+// sedangkan name dan locale opsional.
+type importColumns struct {
+	email, role, name, locale, shortCode int
+}
+
+// ImportInvitations membuat undangan dari berkas CSV yang diunggah sebagai multipart. Berkas
+// dibaca secara streaming per kelompok baris sehingga tidak pernah dimuat seluruhnya ke
+// memori. Setiap baris divalidasi dengan aturan yang sama seperti CreateInvitation, dan
+// respons berupa laporan CSV berisi status setiap baris. Dengan ?async=true, baris disimpan
+// ke job dan respons 202 berisi ID job untuk dipantau melalui GetJob.
+func (h *InvitationHandler) ImportInvitations(c *gin.Context) {
+	tenantID, inviterID, ok := inviterFromContext(c)
+	if !ok || !h.checkAsync(c) {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxImportFileSize)
+	file, err := openImportFile(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1 // Kolom opsional di akhir baris boleh dihilangkan.
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "gagal membaca header CSV"})
+		return
+	}
+	columns, err := parseImportHeader(header)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if isAsync(c) {
+		h.importAsync(c, reader, columns, tenantID, inviterID)
+		return
+	}
+
+	// Sejak titik ini laporan ditulis secara streaming; kesalahan berikutnya dicatat
+	// sebagai baris laporan karena status HTTP sudah terkirim.
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="invitation-import-report.csv"`)
+	c.Status(http.StatusOK)
+	report := csv.NewWriter(c.Writer)
+	_ = report.Write([]string{"row", "email", "status", "invitation_id", "error"})
+
+	_ = streamImportRows(reader, columns, func(results []rowResult, pending []pendingRow) error {
+		h.createRows(c, tenantID, inviterID, pending, results)
+		writeReportRows(report, results)
+		return nil
+	})
+	report.Flush()
+}
+
+// importAsync menyimpan baris CSV ke job baru dan mengembalikan 202 dengan ID job.
+func (h *InvitationHandler) importAsync(c *gin.Context, reader *csv.Reader, columns importColumns, tenantID, inviterID string) {
+	job, ok := h.createJob(c, tenantID, inviterID, jobSourceImport)
+	if !ok {
+		return
+	}
+	err := streamImportRows(reader, columns, func(results []rowResult, pending []pendingRow) error {
+		return h.appendJobRows(c, job.ID, tenantID, inviterID, pending, results)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal menyimpan baris impor ke job"})
+		return
+	}
+	h.startJob(c, job)
+}
+
+// streamImportRows membaca baris data CSV, memvalidasinya, dan memanggil handle untuk setiap
+// kelompok baris. results berisi hasil awal setiap baris dalam kelompok, sedangkan pending
+// berisi baris valid yang perlu dibuat. Pembacaan berhenti pada error pertama dari handle.
+func streamImportRows(reader *csv.Reader, columns importColumns, handle func(results []rowResult, pending []pendingRow) error) error {
+	checker := newRowChecker()
+	results := make([]rowResult, 0, importBatchSize)
+	pending := make([]pendingRow, 0, importBatchSize)
+	flush := func() error {
+		err := handle(results, pending)
+		results, pending = results[:0], pending[:0]
+		return err
+	}
+
+	for rows := 0; ; rows++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if rows >= MaxImportRows {
+			results = append(results, rowResult{Status: service.RowStatusFailed, Error: fmt.Sprintf("impor dihentikan: berkas melebihi %d baris", MaxImportRows)})
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			results = append(results, rowResult{Index: parseErr.Line, Status: service.RowStatusInvalid, Error: parseErr.Err.Error()})
+		} else if err != nil {
+			results = append(results, rowResult{Status: service.RowStatusFailed, Error: "impor dihentikan: gagal membaca berkas"})
+			break
+		} else {
+			// Nomor baris mengikuti berkas asli agar mudah dicocokkan dengan spreadsheet.
+			line, _ := reader.FieldPos(0)
+			row := columns.row(record)
+			result := checker.check(line, &row)
+			results = append(results, result)
+			if result.Status == "" {
+				pending = append(pending, pendingRow{req: row, result: len(results) - 1})
+			}
+		}
+		if len(results) >= importBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// openImportFile mengembalikan isi part multipart berkas CSV tanpa menyalinnya ke memori atau disk.
+func openImportFile(c *gin.Context) (io.Reader, error) {
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, errImportFileMissing
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, errImportFileMissing
+		} else if err != nil {
+			return nil, fmt.Errorf("gagal membaca unggahan: %w", err)
+		}
+		if part.FormName() == importFormField && part.FileName() != "" {
+			return part, nil
+		}
+	}
+}
+
+// parseImportHeader mencari posisi kolom berdasarkan header CSV tanpa memperhatikan huruf besar.
+// BOM UTF-8 yang ditambahkan Excel pada kolom pertama diabaikan.
+func parseImportHeader(header []string) (importColumns, error) {
+	columns := importColumns{email: -1, role: -1, name: -1, locale: -1, shortCode: -1}
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) {
+		case "email":
+			columns.email = i
+		case "role":
+			columns.role = i
+		case "name":
+			columns.name = i
+		case "locale":
+			columns.locale = i
+		case "short_code":
+			columns.shortCode = i
+		}
+	}
+	if columns.email < 0 || columns.role < 0 {
+		return columns, errors.New("header CSV wajib memiliki kolom email dan role")
+	}
+	return columns, nil
+}
+
+// row menyusun masukan undangan dari satu baris CSV. Kolom yang tidak ada dianggap kosong, dan
+// kolom short_code yang bukan boolean dianggap false.
+func (ic importColumns) row(record []string) createInvitationRequest {
+	field := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	shortCode, _ := strconv.ParseBool(field(ic.shortCode))
+	return createInvitationRequest{
+		Email:     field(ic.email),
+		Role:      field(ic.role),
+		Name:      field(ic.name),
+		Locale:    field(ic.locale),
+		ShortCode: shortCode,
+	}
+}
+
+func writeReportRows(report *csv.Writer, results []rowResult) {
+	for _, result := range results {
+		_ = report.Write([]string{strconv.Itoa(result.Index), csvCell(result.Email), result.Status, csvCell(result.ID), csvCell(result.Error)})
+	}
+	report.Flush()
+}
+
+// csvCell mencegah formula injection ketika laporan dibuka di aplikasi spreadsheet: nilai dari
+// unggahan yang diawali =, +, -, @, tab atau carriage return diberi awalan tanda kutip tunggal
+// sehingga dibaca sebagai teks.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
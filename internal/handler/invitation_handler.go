@@ -2,52 +2,229 @@
 package handler
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	commonauth "github.com/Lumina-Enterprise-Solutions/prism-common-libs/auth"
 	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/service"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
 )
 
+// CodeSeatLimitExceeded adalah kode error ketika undangan ditolak karena kursi paket tenant
+// sudah habis, agar frontend dapat menawarkan peningkatan paket.
+const CodeSeatLimitExceeded = "seat_limit_exceeded"
+
 type InvitationHandler struct {
-	service service.InvitationService
+	service    service.InvitationService
+	jobs       service.JobService
+	authorizer Authorizer
+	permission PermissionMiddleware
+	guard      service.TokenGuard
+	codeGuard  service.TokenGuard
 }
 
-func NewInvitationHandler(svc service.InvitationService) *InvitationHandler {
-	return &InvitationHandler{service: svc}
+// HandlerOption mengonfigurasi dependensi opsional InvitationHandler.
+type HandlerOption func(*InvitationHandler)
+
+// WithJobService mengaktifkan mode async pada undangan massal dan impor CSV.
+func WithJobService(jobs service.JobService) HandlerOption {
+	return func(h *InvitationHandler) {
+		h.jobs = jobs
+	}
 }
 
-func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
-	var req struct {
-		Email string `json:"email" binding:"required,email"`
-		Role  string `json:"role" binding:"required"`
+// WithTokenGuard mengaktifkan pembatasan tebakan token pada endpoint publik berbasis token.
+func WithTokenGuard(guard service.TokenGuard) HandlerOption {
+	return func(h *InvitationHandler) {
+		h.guard = guard
+	}
+}
+
+// WithShortCodeGuard memakai guard terpisah dengan batas lebih ketat untuk token berbentuk
+// kode undangan pendek.
+func WithShortCodeGuard(guard service.TokenGuard) HandlerOption {
+	return func(h *InvitationHandler) {
+		h.codeGuard = guard
+	}
+}
+
+func NewInvitationHandler(svc service.InvitationService, opts ...HandlerOption) *InvitationHandler {
+	h := &InvitationHandler{service: svc}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
+}
+
+// createInvitationRequest adalah masukan pembuatan satu undangan. Aturan validasinya juga
+// dipakai untuk setiap baris pada pembuatan undangan massal.
+type createInvitationRequest struct {
+	Email   string `json:"email" binding:"required,email"`
+	Role    string `json:"role" binding:"required"`
+	Message string `json:"message" binding:"max=500"`
+	Name    string `json:"name" binding:"max=200"`
+	Locale  string `json:"locale" binding:"omitempty,bcp47_language_tag"`
+	// ExpiresIn (dalam detik) atau ExpiresAt opsional memilih masa berlaku undangan. Nilainya
+	// dijepit ke batas masa berlaku tenant oleh service. Batas atas 10 tahun mencegah overflow
+	// saat ExpiresIn dikonversi ke time.Duration.
+	ExpiresIn int64      `json:"expires_in" binding:"omitempty,min=1,max=315360000,excluded_with=ExpiresAt"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	// ShortCode meminta kode pendek yang dapat diketik sebagai pengganti tautan, untuk penerima
+	// yang menerima undangan di kertas atau SMS.
+	ShortCode bool `json:"short_code"`
+}
+
+// CreateInvitation membuat undangan baru. Jika email sudah memiliki undangan aktif di tenant
+// yang sama, respons 409 berisi ID undangan tersebut. Dengan ?upsert=true, undangan yang ada
+// diperbarui dengan token dan role baru lalu dikirim ulang, dengan respons 200. Jika kursi
+// paket tenant sudah habis, respons 403 berisi kode seat_limit_exceeded.
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	var req createInvitationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	tenantID, inviterID, ok := inviterFromContext(c)
+	if !ok {
+		return
+	}
+
+	createReq := newCreateRequest(c, req, tenantID, inviterID)
+	createReq.Upsert, _ = strconv.ParseBool(c.Query("upsert"))
+	invitation, err := h.service.CreateInvitation(c.Request.Context(), createReq)
+	if err != nil {
+		var dup *service.DuplicateInvitationError
+		switch {
+		case errors.As(err, &dup):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "id": dup.InvitationID})
+		case errors.Is(err, service.ErrUnknownRole), errors.Is(err, service.ErrShortCodesDisabled), errors.Is(err, service.ErrExpiryInPast):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrRoleNotGrantable):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrRateLimited):
+			setRetryAfter(c, err)
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrSeatLimitExceeded):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": CodeSeatLimitExceeded})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal membuat undangan"})
+		}
+		return
+	}
+	if invitation.UpdatedAt != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "undangan berhasil diperbarui dan dikirim ulang",
+			"id":         invitation.ID,
+			"expires_at": invitation.ExpiresAt,
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "undangan berhasil dikirim",
+		"id":         invitation.ID,
+		"expires_at": invitation.ExpiresAt,
+	})
+}
+
+// inviterFromContext membaca tenant dan pengundang dari token. Jika salah satunya tidak ada,
+// respons 401 sudah ditulis dan ok bernilai false.
+func inviterFromContext(c *gin.Context) (tenantID, inviterID string, ok bool) {
 	tenantID, err := commonauth.GetTenantID(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "tenant_id tidak ditemukan di dalam token"})
-		return
+		return "", "", false
 	}
 
-	inviterID, err := commonauth.GetUserID(c)
+	inviterID, err = commonauth.GetUserID(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "user_id tidak ditemukan di dalam token"})
+		return "", "", false
+	}
+	return tenantID, inviterID, true
+}
+
+// inviterRoleFromContext membaca role pengundang dari klaim "role" pada token. String kosong
+// dikembalikan jika klaim tidak ada, sehingga RoleValidator menolak role apa pun.
+func inviterRoleFromContext(c *gin.Context) string {
+	claims, _ := c.Get(commonauth.ClaimsKey)
+	mapClaims, _ := claims.(jwt.MapClaims)
+	role, _ := mapClaims["role"].(string)
+	return role
+}
+
+// newCreateRequest melengkapi masukan pengguna dengan tenant, pengundang dan metadata audit.
+func newCreateRequest(c *gin.Context, req createInvitationRequest, tenantID, inviterID string) service.CreateInvitationRequest {
+	createReq := service.CreateInvitationRequest{
+		Email:       req.Email,
+		Role:        req.Role,
+		TenantID:    tenantID,
+		InviterID:   inviterID,
+		InviterRole: inviterRoleFromContext(c),
+		SourceIP:    c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		Message:     req.Message,
+		Name:        req.Name,
+		Locale:      req.Locale,
+		ExpiresIn:   time.Duration(req.ExpiresIn) * time.Second,
+		ShortCode:   req.ShortCode,
+	}
+	if req.ExpiresAt != nil {
+		createReq.ExpiresAt = *req.ExpiresAt
+	}
+	return createReq
+}
+
+func (h *InvitationHandler) ValidateInvitation(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token wajib diisi"})
+		return
+	}
+
+	if !h.checkTokenGuard(c, req.Token) {
 		return
 	}
 
-	_, err = h.service.CreateInvitation(c.Request.Context(), req.Email, req.Role, tenantID, inviterID)
+	data, err := h.service.ValidateInvitation(c.Request.Context(), req.Token)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal membuat undangan"})
+		h.writeTokenError(c, req.Token, err)
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"message": "undangan berhasil dikirim"})
+
+	c.JSON(http.StatusOK, data)
 }
 
-func (h *InvitationHandler) ValidateInvitation(c *gin.Context) {
+// PreviewInvitation menampilkan detail undangan tanpa mengonsumsi tokennya.
+func (h *InvitationHandler) PreviewInvitation(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token wajib diisi"})
+		return
+	}
+
+	if !h.checkTokenGuard(c, token) {
+		return
+	}
+
+	data, err := h.service.PreviewInvitation(c.Request.Context(), token)
+	if err != nil {
+		h.writeTokenError(c, token, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// ReserveInvitation memulai alur dua fase dengan mereservasi undangan selama masa lease.
+func (h *InvitationHandler) ReserveInvitation(c *gin.Context) {
 	var req struct {
 		Token string `json:"token" binding:"required"`
 	}
@@ -56,11 +233,212 @@ func (h *InvitationHandler) ValidateInvitation(c *gin.Context) {
 		return
 	}
 
-	data, err := h.service.ValidateInvitation(c.Request.Context(), req.Token)
+	if !h.checkTokenGuard(c, req.Token) {
+		return
+	}
+
+	reservation, err := h.service.ReserveInvitation(c.Request.Context(), req.Token)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		h.writeTokenError(c, req.Token, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// CommitInvitation menyelesaikan alur dua fase dan mengonsumsi undangan yang direservasi.
+func (h *InvitationHandler) CommitInvitation(c *gin.Context) {
+	var req reservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token dan reservation_id wajib diisi"})
+		return
+	}
+
+	if !h.checkTokenGuard(c, req.Token) {
+		return
+	}
+
+	data, err := h.service.CommitInvitation(c.Request.Context(), req.Token, req.ReservationID)
+	if err != nil {
+		h.writeTokenError(c, req.Token, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, data)
 }
+
+// ReleaseInvitation melepas reservasi agar pendaftaran yang gagal dapat dicoba lagi.
+func (h *InvitationHandler) ReleaseInvitation(c *gin.Context) {
+	var req reservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token dan reservation_id wajib diisi"})
+		return
+	}
+
+	if !h.checkTokenGuard(c, req.Token) {
+		return
+	}
+
+	if err := h.service.ReleaseInvitation(c.Request.Context(), req.Token, req.ReservationID); err != nil {
+		h.writeTokenError(c, req.Token, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type reservationRequest struct {
+	Token         string `json:"token" binding:"required"`
+	ReservationID string `json:"reservation_id" binding:"required"`
+}
+
+// guardFor memilih TokenGuard untuk token: guard kode pendek jika token berbentuk kode dan
+// guard tersebut dikonfigurasi, atau guard token biasa.
+func (h *InvitationHandler) guardFor(token string) service.TokenGuard {
+	if h.codeGuard != nil && service.IsShortCode(token) {
+		return h.codeGuard
+	}
+	return h.guard
+}
+
+// checkTokenGuard menolak permintaan dengan 429 jika IP pemanggil atau seluruh endpoint token
+// sedang dikunci. Jika status penguncian tidak dapat dibaca, permintaan tetap dilayani agar
+// gangguan Redis tidak memblokir pendaftaran.
+func (h *InvitationHandler) checkTokenGuard(c *gin.Context, token string) bool {
+	guard := h.guardFor(token)
+	if guard == nil {
+		return true
+	}
+	err := guard.Check(c.Request.Context(), c.ClientIP())
+	if errors.Is(err, service.ErrTooManyAttempts) {
+		setRetryAfter(c, err)
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return false
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Gagal memeriksa penguncian token undangan")
+	}
+	return true
+}
+
+// writeTokenError memetakan error operasi berbasis token ke respons HTTP. Token tidak valid
+// dicatat ke TokenGuard sebagai percobaan gagal dari IP pemanggil.
+func (h *InvitationHandler) writeTokenError(c *gin.Context, token string, err error) {
+	if guard := h.guardFor(token); guard != nil && errors.Is(err, service.ErrInvalidToken) {
+		if guardErr := guard.RecordFailure(c.Request.Context(), c.ClientIP()); guardErr != nil {
+			log.Error().Err(guardErr).Msg("Gagal mencatat percobaan token undangan tidak valid")
+		}
+	}
+	switch {
+	case errors.Is(err, service.ErrInvitationReserved), errors.Is(err, service.ErrReservationMismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	}
+}
+
+// ListInvitations mengembalikan undangan yang masih berlaku untuk tenant pemanggil.
+// Query yang didukung: role, email_prefix, cursor, dan limit.
+func (h *InvitationHandler) ListInvitations(c *gin.Context) {
+	tenantID, err := commonauth.GetTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "tenant_id tidak ditemukan di dalam token"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(service.DefaultListLimit)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit harus berupa angka"})
+		return
+	}
+
+	filter := service.ListFilter{
+		Role:        c.Query("role"),
+		EmailPrefix: c.Query("email_prefix"),
+		Cursor:      c.Query("cursor"),
+		Limit:       limit,
+	}
+	page, err := h.service.ListInvitations(c.Request.Context(), tenantID, filter)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal memuat daftar undangan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// RevokeInvitation membatalkan undangan yang belum diterima berdasarkan ID-nya.
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	tenantID, err := commonauth.GetTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "tenant_id tidak ditemukan di dalam token"})
+		return
+	}
+
+	revokedBy, err := commonauth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user_id tidak ditemukan di dalam token"})
+		return
+	}
+
+	record, err := h.service.RevokeInvitation(c.Request.Context(), tenantID, c.Param("id"), revokedBy)
+	if err != nil {
+		if errors.Is(err, service.ErrInvitationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal mencabut undangan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+// ResendInvitation mengirim ulang undangan dengan token baru dan masa berlaku yang diperpanjang.
+func (h *InvitationHandler) ResendInvitation(c *gin.Context) {
+	tenantID, err := commonauth.GetTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "tenant_id tidak ditemukan di dalam token"})
+		return
+	}
+
+	resentBy, err := commonauth.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user_id tidak ditemukan di dalam token"})
+		return
+	}
+
+	invitation, err := h.service.ResendInvitation(c.Request.Context(), tenantID, c.Param("id"), resentBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvitationNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrResendLimitReached), errors.Is(err, service.ErrShortCodesDisabled):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrResendCooldown):
+			setRetryAfter(c, err)
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal mengirim ulang undangan"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "undangan berhasil dikirim ulang",
+		"id":         invitation.ID,
+		"expires_at": invitation.ExpiresAt,
+	})
+}
+
+// setRetryAfter mengisi header Retry-After (dalam detik) jika error membawa informasi jeda.
+func setRetryAfter(c *gin.Context, err error) {
+	var retryErr *service.RetryAfterError
+	if errors.As(err, &retryErr) && retryErr.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryErr.RetryAfter.Seconds()))))
+	}
+}
@@ -3,14 +3,19 @@ package handler
 import (
 	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	commonauth "github.com/Lumina-Enterprise-Solutions/prism-common-libs/auth"
 	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/service"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -20,9 +25,17 @@ type MockInvitationService struct {
 	mock.Mock
 }
 
-func (m *MockInvitationService) CreateInvitation(ctx context.Context, email, role, tenantID, inviterID string) (string, error) {
-	args := m.Called(ctx, email, role, tenantID, inviterID)
-	return args.String(0), args.Error(1)
+func (m *MockInvitationService) CreateInvitation(ctx context.Context, req service.CreateInvitationRequest) (*service.InvitationData, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.InvitationData), args.Error(1)
+}
+
+func (m *MockInvitationService) CreateInvitations(ctx context.Context, reqs []service.CreateInvitationRequest) []service.BulkCreateResult {
+	args := m.Called(ctx, reqs)
+	return args.Get(0).([]service.BulkCreateResult)
 }
 
 func (m *MockInvitationService) ValidateInvitation(ctx context.Context, token string) (*service.InvitationData, error) {
@@ -33,12 +46,70 @@ func (m *MockInvitationService) ValidateInvitation(ctx context.Context, token st
 	return args.Get(0).(*service.InvitationData), args.Error(1)
 }
 
+func (m *MockInvitationService) ListInvitations(ctx context.Context, tenantID string, filter service.ListFilter) (*service.InvitationPage, error) {
+	args := m.Called(ctx, tenantID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.InvitationPage), args.Error(1)
+}
+
+func (m *MockInvitationService) RevokeInvitation(ctx context.Context, tenantID, invitationID, revokedBy string) (*service.RevocationRecord, error) {
+	args := m.Called(ctx, tenantID, invitationID, revokedBy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.RevocationRecord), args.Error(1)
+}
+
+func (m *MockInvitationService) ResendInvitation(ctx context.Context, tenantID, invitationID, resentBy string) (*service.InvitationData, error) {
+	args := m.Called(ctx, tenantID, invitationID, resentBy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.InvitationData), args.Error(1)
+}
+
+func (m *MockInvitationService) PreviewInvitation(ctx context.Context, token string) (*service.InvitationData, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.InvitationData), args.Error(1)
+}
+
+func (m *MockInvitationService) ReserveInvitation(ctx context.Context, token string) (*service.Reservation, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.Reservation), args.Error(1)
+}
+
+func (m *MockInvitationService) CommitInvitation(ctx context.Context, token, reservationID string) (*service.InvitationData, error) {
+	args := m.Called(ctx, token, reservationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.InvitationData), args.Error(1)
+}
+
+func (m *MockInvitationService) ReleaseInvitation(ctx context.Context, token, reservationID string) error {
+	args := m.Called(ctx, token, reservationID)
+	return args.Error(0)
+}
+
 func setupTestRouter(handler *InvitationHandler) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
 	group := router.Group("/invitations")
 	group.POST("", handler.CreateInvitation)
 	group.POST("/validate", handler.ValidateInvitation)
+	group.GET("/preview", handler.PreviewInvitation)
+	group.POST("/accept", handler.ValidateInvitation)
+	group.POST("/reserve", handler.ReserveInvitation)
+	group.POST("/commit", handler.CommitInvitation)
+	group.POST("/release", handler.ReleaseInvitation)
 	return router
 }
 
@@ -50,23 +121,228 @@ func TestInvitationHandler_CreateInvitation(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
 	router.POST("/invitations", func(c *gin.Context) {
-		c.Set("tenantID", "test-tenant")
-		c.Set("userID", "test-inviter")
+		c.Set(commonauth.TenantIDKey, "test-tenant")
+		c.Set(commonauth.UserIDKey, "test-inviter")
 		handler.CreateInvitation(c)
 	})
 
 	t.Run("Success", func(t *testing.T) {
-		mockService.On("CreateInvitation", mock.Anything, "test@example.com", "admin", "test-tenant", "test-inviter").Return("new-token", nil).Once()
+		expiresAt := time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)
+		created := &service.InvitationData{ID: "inv-1", Email: "test@example.com", Role: "admin", ExpiresAt: expiresAt}
+		expectedReq := service.CreateInvitationRequest{
+			Email:     "test@example.com",
+			Role:      "admin",
+			TenantID:  "test-tenant",
+			InviterID: "test-inviter",
+			SourceIP:  "192.0.2.10",
+			UserAgent: "prism-test-agent",
+			Message:   "Selamat datang",
+		}
+		mockService.On("CreateInvitation", mock.Anything, expectedReq).Return(created, nil).Once()
 
-		payload := `{"email": "test@example.com", "role": "admin"}`
+		payload := `{"email": "test@example.com", "role": "admin", "message": "Selamat datang"}`
 		req, _ := http.NewRequest(http.MethodPost, "/invitations", bytes.NewBufferString(payload))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "prism-test-agent")
+		req.RemoteAddr = "192.0.2.10:54321"
 		rr := httptest.NewRecorder()
 
 		router.ServeHTTP(rr, req)
 
 		assert.Equal(t, http.StatusCreated, rr.Code)
-		assert.JSONEq(t, `{"message": "undangan berhasil dikirim"}`, rr.Body.String())
+		assert.JSONEq(t, `{"message": "undangan berhasil dikirim", "id": "inv-1", "expires_at": "2025-01-09T00:00:00Z"}`, rr.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Conflict - Active Invitation Exists", func(t *testing.T) {
+		mockService.On("CreateInvitation", mock.Anything, mock.MatchedBy(func(req service.CreateInvitationRequest) bool {
+			return req.Email == "dup@example.com" && !req.Upsert
+		})).Return(nil, &service.DuplicateInvitationError{InvitationID: "inv-existing"}).Once()
+
+		payload := `{"email": "dup@example.com", "role": "admin"}`
+		req, _ := http.NewRequest(http.MethodPost, "/invitations", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.JSONEq(t, `{"error": "undangan aktif untuk email ini sudah ada", "id": "inv-existing"}`, rr.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Upsert Updates Existing Invitation", func(t *testing.T) {
+		expiresAt := time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)
+		updatedAt := expiresAt.Add(-7 * 24 * time.Hour)
+		updated := &service.InvitationData{ID: "inv-existing", Email: "dup@example.com", Role: "editor", ExpiresAt: expiresAt, UpdatedAt: &updatedAt}
+		mockService.On("CreateInvitation", mock.Anything, mock.MatchedBy(func(req service.CreateInvitationRequest) bool {
+			return req.Email == "dup@example.com" && req.Role == "editor" && req.Upsert
+		})).Return(updated, nil).Once()
+
+		payload := `{"email": "dup@example.com", "role": "editor"}`
+		req, _ := http.NewRequest(http.MethodPost, "/invitations?upsert=true", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"message": "undangan berhasil diperbarui dan dikirim ulang", "id": "inv-existing", "expires_at": "2025-01-09T00:00:00Z"}`, rr.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Role Rejected", func(t *testing.T) {
+		mockService.On("CreateInvitation", mock.Anything, mock.MatchedBy(func(req service.CreateInvitationRequest) bool {
+			return req.Role == "admn"
+		})).Return(nil, service.ErrUnknownRole).Once()
+		mockService.On("CreateInvitation", mock.Anything, mock.MatchedBy(func(req service.CreateInvitationRequest) bool {
+			return req.Role == "owner"
+		})).Return(nil, service.ErrRoleNotGrantable).Once()
+
+		for role, code := range map[string]int{"admn": http.StatusBadRequest, "owner": http.StatusForbidden} {
+			payload := `{"email": "role@example.com", "role": "` + role + `"}`
+			req, _ := http.NewRequest(http.MethodPost, "/invitations", bytes.NewBufferString(payload))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, code, rr.Code, role)
+		}
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Rate Limited", func(t *testing.T) {
+		limited := &service.RetryAfterError{Err: service.ErrRateLimited, RetryAfter: 90 * time.Second}
+		mockService.On("CreateInvitation", mock.Anything, mock.MatchedBy(func(req service.CreateInvitationRequest) bool {
+			return req.Email == "limited@example.com"
+		})).Return(nil, limited).Once()
+
+		req, _ := http.NewRequest(http.MethodPost, "/invitations", bytes.NewBufferString(`{"email": "limited@example.com", "role": "viewer"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "90", rr.Header().Get("Retry-After"))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Custom Expiry", func(t *testing.T) {
+		expiresAt := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+		mockService.On("CreateInvitation", mock.Anything, mock.MatchedBy(func(req service.CreateInvitationRequest) bool {
+			return req.Email == "contractor@example.com" && req.ExpiresIn == 24*time.Hour && req.ExpiresAt.IsZero()
+		})).Return(&service.InvitationData{ID: "inv-contractor", ExpiresAt: expiresAt}, nil).Once()
+
+		req, _ := http.NewRequest(http.MethodPost, "/invitations", bytes.NewBufferString(`{"email": "contractor@example.com", "role": "viewer", "expires_in": 86400}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"expires_at":"2025-02-01T00:00:00Z"`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Expires In Too Large", func(t *testing.T) {
+		// 1e10 detik melampaui time.Duration maksimum dan akan menjadi negatif jika dikonversi.
+		payload := `{"email": "forever@example.com", "role": "viewer", "expires_in": 10000000000}`
+		req, _ := http.NewRequest(http.MethodPost, "/invitations", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockService.AssertNotCalled(t, "CreateInvitation", mock.Anything, mock.MatchedBy(func(req service.CreateInvitationRequest) bool {
+			return req.Email == "forever@example.com"
+		}))
+	})
+
+	t.Run("Expires At In The Past", func(t *testing.T) {
+		mockService.On("CreateInvitation", mock.Anything, mock.MatchedBy(func(req service.CreateInvitationRequest) bool {
+			return req.Email == "late@example.com"
+		})).Return(nil, service.ErrExpiryInPast).Once()
+
+		payload := `{"email": "late@example.com", "role": "viewer", "expires_at": "2020-01-01T00:00:00Z"}`
+		req, _ := http.NewRequest(http.MethodPost, "/invitations", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), service.ErrExpiryInPast.Error())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Conflicting Expiry Fields", func(t *testing.T) {
+		payload := `{"email": "both@example.com", "role": "viewer", "expires_in": 3600, "expires_at": "2025-02-01T00:00:00Z"}`
+		req, _ := http.NewRequest(http.MethodPost, "/invitations", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockService.AssertNotCalled(t, "CreateInvitation", mock.Anything, mock.MatchedBy(func(req service.CreateInvitationRequest) bool {
+			return req.Email == "both@example.com"
+		}))
+	})
+
+	t.Run("Seat Limit Exceeded", func(t *testing.T) {
+		mockService.On("CreateInvitation", mock.Anything, mock.MatchedBy(func(req service.CreateInvitationRequest) bool {
+			return req.Email == "seat@example.com"
+		})).Return(nil, service.ErrSeatLimitExceeded).Once()
+
+		req, _ := http.NewRequest(http.MethodPost, "/invitations", bytes.NewBufferString(`{"email": "seat@example.com", "role": "viewer"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.JSONEq(t, `{"error": "kuota kursi paket tenant sudah habis", "code": "seat_limit_exceeded"}`, rr.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Short Code Requested While Disabled", func(t *testing.T) {
+		mockService.On("CreateInvitation", mock.Anything, mock.MatchedBy(func(req service.CreateInvitationRequest) bool {
+			return req.Email == "picker@example.com" && req.ShortCode
+		})).Return(nil, service.ErrShortCodesDisabled).Once()
+
+		req, _ := http.NewRequest(http.MethodPost, "/invitations", bytes.NewBufferString(`{"email": "picker@example.com", "role": "viewer", "short_code": true}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Inviter Role From Claims", func(t *testing.T) {
+		claimsRouter := gin.New()
+		claimsRouter.POST("/invitations", func(c *gin.Context) {
+			c.Set(commonauth.TenantIDKey, "test-tenant")
+			c.Set(commonauth.UserIDKey, "test-inviter")
+			c.Set(commonauth.ClaimsKey, jwt.MapClaims{"role": "manager"})
+			handler.CreateInvitation(c)
+		})
+		created := &service.InvitationData{ID: "inv-2", Email: "claims@example.com", Role: "viewer"}
+		mockService.On("CreateInvitation", mock.Anything, mock.MatchedBy(func(req service.CreateInvitationRequest) bool {
+			return req.Email == "claims@example.com" && req.InviterRole == "manager"
+		})).Return(created, nil).Once()
+
+		req, _ := http.NewRequest(http.MethodPost, "/invitations", bytes.NewBufferString(`{"email": "claims@example.com", "role": "viewer"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		claimsRouter.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
 		mockService.AssertExpectations(t)
 	})
 
@@ -119,3 +395,669 @@ func TestInvitationHandler_ValidateInvitation(t *testing.T) {
 		mockService.AssertExpectations(t)
 	})
 }
+
+func TestInvitationHandler_ListInvitations(t *testing.T) {
+	mockService := new(MockInvitationService)
+	handler := NewInvitationHandler(mockService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/invitations", func(c *gin.Context) {
+		c.Set(commonauth.TenantIDKey, "test-tenant")
+		handler.ListInvitations(c)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		expectedFilter := service.ListFilter{Role: "admin", EmailPrefix: "ali", Cursor: "abc", Limit: 5}
+		page := &service.InvitationPage{
+			Invitations: []service.InvitationData{{Email: "alice@example.com", Role: "admin", TenantID: "test-tenant"}},
+			NextCursor:  "next",
+		}
+		mockService.On("ListInvitations", mock.Anything, "test-tenant", expectedFilter).Return(page, nil).Once()
+
+		req, _ := http.NewRequest(http.MethodGet, "/invitations?role=admin&email_prefix=ali&cursor=abc&limit=5", nil)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		expectedJSON, _ := json.Marshal(page)
+		assert.JSONEq(t, string(expectedJSON), rr.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Bad Request - Invalid Cursor", func(t *testing.T) {
+		mockService.On("ListInvitations", mock.Anything, "test-tenant", mock.Anything).Return(nil, service.ErrInvalidCursor).Once()
+
+		req, _ := http.NewRequest(http.MethodGet, "/invitations?cursor=rusak", nil)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestInvitationHandler_RevokeInvitation(t *testing.T) {
+	mockService := new(MockInvitationService)
+	handler := NewInvitationHandler(mockService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.DELETE("/invitations/:id", func(c *gin.Context) {
+		c.Set(commonauth.TenantIDKey, "test-tenant")
+		c.Set(commonauth.UserIDKey, "test-admin")
+		handler.RevokeInvitation(c)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		record := &service.RevocationRecord{InvitationID: "inv-1", TenantID: "test-tenant", RevokedBy: "test-admin"}
+		mockService.On("RevokeInvitation", mock.Anything, "test-tenant", "inv-1", "test-admin").Return(record, nil).Once()
+
+		req, _ := http.NewRequest(http.MethodDelete, "/invitations/inv-1", nil)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		expectedJSON, _ := json.Marshal(record)
+		assert.JSONEq(t, string(expectedJSON), rr.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockService.On("RevokeInvitation", mock.Anything, "test-tenant", "missing", "test-admin").Return(nil, service.ErrInvitationNotFound).Once()
+
+		req, _ := http.NewRequest(http.MethodDelete, "/invitations/missing", nil)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestInvitationHandler_ResendInvitation(t *testing.T) {
+	mockService := new(MockInvitationService)
+	handler := NewInvitationHandler(mockService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/invitations/:id/resend", func(c *gin.Context) {
+		c.Set(commonauth.TenantIDKey, "test-tenant")
+		c.Set(commonauth.UserIDKey, "test-admin")
+		handler.ResendInvitation(c)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		expiresAt := time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)
+		resent := &service.InvitationData{ID: "inv-1", ExpiresAt: expiresAt, ResendCount: 1}
+		mockService.On("ResendInvitation", mock.Anything, "test-tenant", "inv-1", "test-admin").Return(resent, nil).Once()
+
+		req, _ := http.NewRequest(http.MethodPost, "/invitations/inv-1/resend", nil)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"message": "undangan berhasil dikirim ulang", "id": "inv-1", "expires_at": "2025-01-09T00:00:00Z"}`, rr.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Too Many Requests - Cooldown", func(t *testing.T) {
+		cooldownErr := &service.RetryAfterError{Err: service.ErrResendCooldown, RetryAfter: 90 * time.Second}
+		mockService.On("ResendInvitation", mock.Anything, "test-tenant", "inv-2", "test-admin").Return(nil, cooldownErr).Once()
+
+		req, _ := http.NewRequest(http.MethodPost, "/invitations/inv-2/resend", nil)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "90", rr.Header().Get("Retry-After"))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Conflict - Limit Reached", func(t *testing.T) {
+		mockService.On("ResendInvitation", mock.Anything, "test-tenant", "inv-3", "test-admin").Return(nil, service.ErrResendLimitReached).Once()
+
+		req, _ := http.NewRequest(http.MethodPost, "/invitations/inv-3/resend", nil)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Conflict - Short Codes Disabled", func(t *testing.T) {
+		mockService.On("ResendInvitation", mock.Anything, "test-tenant", "inv-4", "test-admin").Return(nil, service.ErrShortCodesDisabled).Once()
+
+		req, _ := http.NewRequest(http.MethodPost, "/invitations/inv-4/resend", nil)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestInvitationHandler_PreviewInvitation(t *testing.T) {
+	mockService := new(MockInvitationService)
+	handler := NewInvitationHandler(mockService)
+	router := setupTestRouter(handler)
+
+	t.Run("Success", func(t *testing.T) {
+		expectedData := &service.InvitationData{Email: "user@example.com", Role: "admin", TenantID: "tenant-x"}
+		mockService.On("PreviewInvitation", mock.Anything, "valid-token").Return(expectedData, nil).Once()
+
+		req, _ := http.NewRequest(http.MethodGet, "/invitations/preview?token=valid-token", nil)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		expectedJSON, _ := json.Marshal(expectedData)
+		assert.JSONEq(t, string(expectedJSON), rr.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Bad Request - Missing Token", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/invitations/preview", nil)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestInvitationHandler_TwoPhaseAccept(t *testing.T) {
+	mockService := new(MockInvitationService)
+	handler := NewInvitationHandler(mockService)
+	router := setupTestRouter(handler)
+
+	t.Run("Reserve Conflict", func(t *testing.T) {
+		mockService.On("ReserveInvitation", mock.Anything, "busy-token").Return(nil, service.ErrInvitationReserved).Once()
+
+		req, _ := http.NewRequest(http.MethodPost, "/invitations/reserve", bytes.NewBufferString(`{"token": "busy-token"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Commit Success", func(t *testing.T) {
+		expectedData := &service.InvitationData{Email: "user@example.com", Role: "admin"}
+		mockService.On("CommitInvitation", mock.Anything, "valid-token", "res-1").Return(expectedData, nil).Once()
+
+		payload := `{"token": "valid-token", "reservation_id": "res-1"}`
+		req, _ := http.NewRequest(http.MethodPost, "/invitations/commit", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Release Success", func(t *testing.T) {
+		mockService.On("ReleaseInvitation", mock.Anything, "valid-token", "res-1").Return(nil).Once()
+
+		payload := `{"token": "valid-token", "reservation_id": "res-1"}`
+		req, _ := http.NewRequest(http.MethodPost, "/invitations/release", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestInvitationHandler_BulkCreateInvitations(t *testing.T) {
+	mockService := new(MockInvitationService)
+	handler := NewInvitationHandler(mockService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/invitations/bulk", func(c *gin.Context) {
+		c.Set(commonauth.TenantIDKey, "test-tenant")
+		c.Set(commonauth.UserIDKey, "test-inviter")
+		handler.BulkCreateInvitations(c)
+	})
+
+	t.Run("Per-Row Results", func(t *testing.T) {
+		expiresAt := time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)
+		mockService.On("CreateInvitations", mock.Anything, mock.MatchedBy(func(reqs []service.CreateInvitationRequest) bool {
+			return len(reqs) == 3 && reqs[0].Email == "a@example.com" && reqs[1].Email == "c@example.com" &&
+				reqs[2].Email == "d@example.com" && reqs[0].TenantID == "test-tenant" && reqs[0].InviterID == "test-inviter"
+		})).Return([]service.BulkCreateResult{
+			{Invitation: &service.InvitationData{ID: "inv-a", ExpiresAt: expiresAt}},
+			{Err: errors.New("redis down")},
+			{Err: &service.DuplicateInvitationError{InvitationID: "inv-d"}},
+		}).Once()
+
+		payload := `{"invitations": [
+			{"email": "a@example.com", "role": "admin"},
+			{"email": "not-an-email", "role": "admin"},
+			{"email": "A@Example.com ", "role": "viewer"},
+			{"email": "c@example.com", "role": "viewer"},
+			{"email": "d@example.com", "role": "viewer"}
+		]}`
+		req, _ := http.NewRequest(http.MethodPost, "/invitations/bulk", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp bulkResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, map[string]int{service.RowStatusCreated: 1, service.RowStatusDuplicate: 2, service.RowStatusInvalid: 1, service.RowStatusFailed: 1}, resp.Summary)
+		statuses := make([]string, len(resp.Results))
+		for i, row := range resp.Results {
+			statuses[i] = row.Status
+		}
+		assert.Equal(t, []string{service.RowStatusCreated, service.RowStatusInvalid, service.RowStatusDuplicate, service.RowStatusFailed, service.RowStatusDuplicate}, statuses)
+		assert.Equal(t, "inv-a", resp.Results[0].ID)
+		assert.Equal(t, "inv-d", resp.Results[4].ID)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Bad Request - Empty List", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/invitations/bulk", bytes.NewBufferString(`{"invitations": []}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestInvitationHandler_ImportInvitations(t *testing.T) {
+	mockService := new(MockInvitationService)
+	handler := NewInvitationHandler(mockService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/invitations/import", func(c *gin.Context) {
+		c.Set(commonauth.TenantIDKey, "test-tenant")
+		c.Set(commonauth.UserIDKey, "test-inviter")
+		handler.ImportInvitations(c)
+	})
+
+	upload := func(t *testing.T, content string) *http.Request {
+		t.Helper()
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("file", "staff.csv")
+		assert.NoError(t, err)
+		_, _ = part.Write([]byte(content))
+		assert.NoError(t, writer.Close())
+		req, _ := http.NewRequest(http.MethodPost, "/invitations/import", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req
+	}
+
+	t.Run("Report Per Row", func(t *testing.T) {
+		mockService.On("CreateInvitations", mock.Anything, mock.MatchedBy(func(reqs []service.CreateInvitationRequest) bool {
+			return len(reqs) == 2 && reqs[0].Email == "budi@example.com" && reqs[0].Name == "Budi" && reqs[0].Locale == "id-ID" &&
+				reqs[1].Email == "sari@example.com" && reqs[1].Locale == ""
+		})).Return([]service.BulkCreateResult{
+			{Invitation: &service.InvitationData{ID: "inv-budi"}},
+			{Invitation: &service.InvitationData{ID: "inv-sari"}},
+		}).Once()
+
+		content := "\ufeffEmail,Role,Name,Locale\n" +
+			"budi@example.com,admin,Budi,id-ID\n" +
+			"bukan-email,admin,,\n" +
+			"BUDI@example.com,viewer,Budi Lagi,\n" +
+			"sari@example.com,viewer\n"
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, upload(t, content))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Header().Get("Content-Disposition"), "attachment")
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"row", "email", "status", "invitation_id", "error"}, records[0])
+		assert.Equal(t, []string{"2", "budi@example.com", service.RowStatusCreated, "inv-budi", ""}, records[1])
+		assert.Equal(t, service.RowStatusInvalid, records[2][2])
+		assert.Equal(t, service.RowStatusDuplicate, records[3][2])
+		assert.Equal(t, []string{"5", "sari@example.com", service.RowStatusCreated, "inv-sari", ""}, records[4])
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Report Escapes Formulas", func(t *testing.T) {
+		mockService.On("CreateInvitations", mock.Anything, mock.MatchedBy(func(reqs []service.CreateInvitationRequest) bool {
+			return len(reqs) == 1 && reqs[0].Email == "budi@example.com"
+		})).Return([]service.BulkCreateResult{
+			{Invitation: &service.InvitationData{ID: "inv-budi"}},
+		}).Once()
+
+		content := "email,role\n" +
+			"budi@example.com,admin\n" +
+			"\"=HYPERLINK(\"\"https://evil.example\"\")\",admin\n" +
+			"@SUM(A1),viewer\n"
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, upload(t, content))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"2", "budi@example.com", service.RowStatusCreated, "inv-budi", ""}, records[1])
+		assert.Equal(t, `'=HYPERLINK("https://evil.example")`, records[2][1])
+		assert.Equal(t, "'@SUM(A1)", records[3][1])
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Bad Request - Missing Role Column", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, upload(t, "email\nbudi@example.com\n"))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Bad Request - No File", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/invitations/import", bytes.NewBufferString(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+// MockJobService adalah mock untuk service.JobService.
+type MockJobService struct {
+	mock.Mock
+}
+
+func (m *MockJobService) CreateJob(ctx context.Context, tenantID, createdBy, source string) (*service.Job, error) {
+	args := m.Called(ctx, tenantID, createdBy, source)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.Job), args.Error(1)
+}
+
+func (m *MockJobService) AppendRows(ctx context.Context, jobID string, rows []service.JobRow, rejected []service.JobRowError) error {
+	args := m.Called(ctx, jobID, rows, rejected)
+	return args.Error(0)
+}
+
+func (m *MockJobService) StartJob(ctx context.Context, jobID string) error {
+	args := m.Called(ctx, jobID)
+	return args.Error(0)
+}
+
+func (m *MockJobService) GetJob(ctx context.Context, tenantID, jobID string) (*service.Job, error) {
+	args := m.Called(ctx, tenantID, jobID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.Job), args.Error(1)
+}
+
+func TestInvitationHandler_Jobs(t *testing.T) {
+	mockService := new(MockInvitationService)
+	mockJobs := new(MockJobService)
+	handler := NewInvitationHandler(mockService, WithJobService(mockJobs))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set(commonauth.TenantIDKey, "test-tenant")
+		c.Set(commonauth.UserIDKey, "test-inviter")
+	})
+	router.POST("/invitations/bulk", handler.BulkCreateInvitations)
+	router.GET("/invitations/jobs/:id", handler.GetJob)
+
+	t.Run("Async Bulk Returns Job", func(t *testing.T) {
+		job := &service.Job{ID: "job-1", TenantID: "test-tenant", Status: service.JobStatusPending}
+		mockJobs.On("CreateJob", mock.Anything, "test-tenant", "test-inviter", "bulk").Return(job, nil).Once()
+		mockJobs.On("AppendRows", mock.Anything, "job-1",
+			mock.MatchedBy(func(rows []service.JobRow) bool {
+				return len(rows) == 1 && rows[0].Row == 0 && rows[0].Request.Email == "a@example.com" && rows[0].Request.TenantID == "test-tenant"
+			}),
+			[]service.JobRowError{{Row: 1, Email: "a@example.com", Status: service.RowStatusDuplicate, Error: "email sudah ada di baris sebelumnya"}},
+		).Return(nil).Once()
+		mockJobs.On("StartJob", mock.Anything, "job-1").Return(nil).Once()
+
+		payload := `{"invitations": [{"email": "a@example.com", "role": "admin"}, {"email": "a@example.com", "role": "viewer"}]}`
+		req, _ := http.NewRequest(http.MethodPost, "/invitations/bulk?async=true", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, "/invitations/jobs/job-1", rr.Header().Get("Location"))
+		assert.JSONEq(t, `{"job_id": "job-1", "status": "queued", "status_url": "/invitations/jobs/job-1"}`, rr.Body.String())
+		mockService.AssertNotCalled(t, "CreateInvitations", mock.Anything, mock.Anything)
+		mockJobs.AssertExpectations(t)
+	})
+
+	t.Run("Get Job", func(t *testing.T) {
+		job := &service.Job{ID: "job-1", TenantID: "test-tenant", Status: service.JobStatusRunning, Total: 10, Processed: 4, Errors: []service.JobRowError{}}
+		mockJobs.On("GetJob", mock.Anything, "test-tenant", "job-1").Return(job, nil).Once()
+
+		req, _ := http.NewRequest(http.MethodGet, "/invitations/jobs/job-1", nil)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var got service.Job
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+		assert.Equal(t, 4, got.Processed)
+		mockJobs.AssertExpectations(t)
+	})
+
+	t.Run("Get Job Not Found", func(t *testing.T) {
+		mockJobs.On("GetJob", mock.Anything, "test-tenant", "missing").Return(nil, service.ErrJobNotFound).Once()
+
+		req, _ := http.NewRequest(http.MethodGet, "/invitations/jobs/missing", nil)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestInvitationHandler_Authorization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// authenticateAs menggantikan JWTMiddleware dengan klaim yang sudah ditentukan.
+	authenticateAs := func(claims jwt.MapClaims) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set(commonauth.TenantIDKey, "test-tenant")
+			c.Set(commonauth.UserIDKey, "test-inviter")
+			c.Set(commonauth.ClaimsKey, claims)
+		}
+	}
+	newRouter := func(svc service.InvitationService, authorizer Authorizer, claims jwt.MapClaims) *gin.Engine {
+		router := gin.New()
+		NewInvitationHandler(svc, WithAuthorizer(authorizer)).RegisterRoutes(router.Group("/invitations"), authenticateAs(claims))
+		return router
+	}
+
+	t.Run("Forbidden Without Permission", func(t *testing.T) {
+		mockService := new(MockInvitationService)
+		router := newRouter(mockService, NewClaimsAuthorizer(""), jwt.MapClaims{"permissions": []interface{}{PermissionInvitationsRead}})
+
+		req, _ := http.NewRequest(http.MethodPost, "/invitations", bytes.NewBufferString(`{"email": "test@example.com", "role": "admin"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.JSONEq(t, `{"error": "akses ditolak: izin 'invitations:create' diperlukan"}`, rr.Body.String())
+		mockService.AssertNotCalled(t, "CreateInvitation", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Allowed By Space Separated Claim", func(t *testing.T) {
+		mockService := new(MockInvitationService)
+		mockService.On("ListInvitations", mock.Anything, "test-tenant", mock.Anything).Return(&service.InvitationPage{Invitations: []service.InvitationData{}}, nil).Once()
+		router := newRouter(mockService, NewClaimsAuthorizer("scope"), jwt.MapClaims{"scope": "invitations:create invitations:read"})
+
+		req, _ := http.NewRequest(http.MethodGet, "/invitations", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Policy Callback", func(t *testing.T) {
+		mockService := new(MockInvitationService)
+		var checked []string
+		policy := AuthorizerFunc(func(c *gin.Context, permission string) (bool, error) {
+			checked = append(checked, permission)
+			return false, errors.New("policy service down")
+		})
+		router := newRouter(mockService, policy, nil)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/invitations/inv-1", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Equal(t, []string{PermissionInvitationsRevoke}, checked)
+	})
+
+	t.Run("Shared Permission Middleware", func(t *testing.T) {
+		mockService := new(MockInvitationService)
+		var checked []string
+		rbac := func(permission string) gin.HandlerFunc {
+			return func(c *gin.Context) {
+				checked = append(checked, permission)
+				c.AbortWithStatus(http.StatusForbidden)
+			}
+		}
+		allowAll := AuthorizerFunc(func(c *gin.Context, permission string) (bool, error) { return true, nil })
+		router := gin.New()
+		NewInvitationHandler(mockService, WithAuthorizer(allowAll), WithPermissionMiddleware(rbac)).RegisterRoutes(router.Group("/invitations"), authenticateAs(nil))
+
+		req, _ := http.NewRequest(http.MethodPost, "/invitations/inv-1/resend", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, []string{PermissionInvitationsResend}, checked)
+		mockService.AssertNotCalled(t, "ResendInvitation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Token Routes Stay Public", func(t *testing.T) {
+		mockService := new(MockInvitationService)
+		mockService.On("PreviewInvitation", mock.Anything, "some-token").Return(&service.InvitationData{ID: "inv-1"}, nil).Once()
+		denyAll := AuthorizerFunc(func(c *gin.Context, permission string) (bool, error) { return false, nil })
+		router := gin.New()
+		NewInvitationHandler(mockService, WithAuthorizer(denyAll)).RegisterRoutes(router.Group("/invitations"), func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusUnauthorized)
+		})
+
+		req, _ := http.NewRequest(http.MethodGet, "/invitations/preview?token=some-token", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+type MockTokenGuard struct {
+	mock.Mock
+}
+
+func (m *MockTokenGuard) Check(ctx context.Context, clientIP string) error {
+	args := m.Called(ctx, clientIP)
+	return args.Error(0)
+}
+
+func (m *MockTokenGuard) RecordFailure(ctx context.Context, clientIP string) error {
+	args := m.Called(ctx, clientIP)
+	return args.Error(0)
+}
+
+func TestInvitationHandler_TokenGuard(t *testing.T) {
+	postValidate := func(router *gin.Engine, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/invitations/validate", bytes.NewBufferString(`{"token": "`+token+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "203.0.113.7:41000"
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Invalid Token Is Recorded", func(t *testing.T) {
+		mockService := new(MockInvitationService)
+		guard := new(MockTokenGuard)
+		router := setupTestRouter(NewInvitationHandler(mockService, WithTokenGuard(guard)))
+		guard.On("Check", mock.Anything, "203.0.113.7").Return(nil).Twice()
+		guard.On("RecordFailure", mock.Anything, "203.0.113.7").Return(nil).Once()
+		mockService.On("ValidateInvitation", mock.Anything, "guess").Return(nil, service.ErrInvalidToken).Once()
+		mockService.On("ValidateInvitation", mock.Anything, "valid-token").Return(&service.InvitationData{ID: "inv-1"}, nil).Once()
+
+		assert.Equal(t, http.StatusNotFound, postValidate(router, "guess").Code)
+		assert.Equal(t, http.StatusOK, postValidate(router, "valid-token").Code)
+		guard.AssertExpectations(t)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Locked Out", func(t *testing.T) {
+		mockService := new(MockInvitationService)
+		guard := new(MockTokenGuard)
+		router := setupTestRouter(NewInvitationHandler(mockService, WithTokenGuard(guard)))
+		guard.On("Check", mock.Anything, "203.0.113.7").Return(&service.RetryAfterError{Err: service.ErrTooManyAttempts, RetryAfter: 90 * time.Second}).Once()
+
+		rr := postValidate(router, "guess")
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "90", rr.Header().Get("Retry-After"))
+		mockService.AssertNotCalled(t, "ValidateInvitation", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Guard Unavailable", func(t *testing.T) {
+		mockService := new(MockInvitationService)
+		guard := new(MockTokenGuard)
+		router := setupTestRouter(NewInvitationHandler(mockService, WithTokenGuard(guard)))
+		guard.On("Check", mock.Anything, mock.Anything).Return(errors.New("redis down")).Once()
+		mockService.On("ValidateInvitation", mock.Anything, "valid-token").Return(&service.InvitationData{ID: "inv-1"}, nil).Once()
+
+		assert.Equal(t, http.StatusOK, postValidate(router, "valid-token").Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Short Codes Use Code Guard", func(t *testing.T) {
+		mockService := new(MockInvitationService)
+		guard := new(MockTokenGuard)
+		codeGuard := new(MockTokenGuard)
+		router := setupTestRouter(NewInvitationHandler(mockService, WithTokenGuard(guard), WithShortCodeGuard(codeGuard)))
+		codeGuard.On("Check", mock.Anything, "203.0.113.7").Return(nil).Once()
+		codeGuard.On("RecordFailure", mock.Anything, "203.0.113.7").Return(nil).Once()
+		guard.On("Check", mock.Anything, "203.0.113.7").Return(nil).Once()
+		guard.On("RecordFailure", mock.Anything, "203.0.113.7").Return(nil).Once()
+		mockService.On("ValidateInvitation", mock.Anything, "K7QF-9M2X").Return(nil, service.ErrInvalidToken).Once()
+		mockService.On("ValidateInvitation", mock.Anything, "3f1c2d4e-0000-4000-8000-000000000000").Return(nil, service.ErrInvalidToken).Once()
+
+		assert.Equal(t, http.StatusNotFound, postValidate(router, "K7QF-9M2X").Code)
+		assert.Equal(t, http.StatusNotFound, postValidate(router, "3f1c2d4e-0000-4000-8000-000000000000").Code)
+		codeGuard.AssertExpectations(t)
+		guard.AssertExpectations(t)
+	})
+}
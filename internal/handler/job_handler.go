@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	commonauth "github.com/Lumina-Enterprise-Solutions/prism-common-libs/auth"
+	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// Sumber job undangan massal.
+const (
+	jobSourceBulk   = "bulk"
+	jobSourceImport = "import"
+)
+
+// GetJob mengembalikan kemajuan, error per baris, dan status penyelesaian job undangan massal.
+func (h *InvitationHandler) GetJob(c *gin.Context) {
+	tenantID, err := commonauth.GetTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "tenant_id tidak ditemukan di dalam token"})
+		return
+	}
+	if h.jobs == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": service.ErrJobNotFound.Error()})
+		return
+	}
+
+	job, err := h.jobs.GetJob(c.Request.Context(), tenantID, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, service.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal memuat status job"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// isAsync melaporkan apakah pemanggil meminta pemrosesan melalui job (?async=true).
+func isAsync(c *gin.Context) bool {
+	async, _ := strconv.ParseBool(c.Query("async"))
+	return async
+}
+
+// checkAsync menolak permintaan async jika job service tidak dikonfigurasi.
+func (h *InvitationHandler) checkAsync(c *gin.Context) bool {
+	if isAsync(c) && h.jobs == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode async tidak tersedia"})
+		return false
+	}
+	return true
+}
+
+func (h *InvitationHandler) createJob(c *gin.Context, tenantID, inviterID, source string) (*service.Job, bool) {
+	job, err := h.jobs.CreateJob(c.Request.Context(), tenantID, inviterID, source)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal membuat job undangan"})
+		return nil, false
+	}
+	return job, true
+}
+
+// appendJobRows menyimpan baris valid ke job untuk dibuat oleh worker dan mencatat baris
+// yang sudah ditolak saat validasi.
+func (h *InvitationHandler) appendJobRows(c *gin.Context, jobID, tenantID, inviterID string, pending []pendingRow, results []rowResult) error {
+	rows := make([]service.JobRow, len(pending))
+	for i, row := range pending {
+		rows[i] = service.JobRow{Row: results[row.result].Index, Request: newCreateRequest(c, row.req, tenantID, inviterID)}
+	}
+	var rejected []service.JobRowError
+	for _, result := range results {
+		if result.Status != "" {
+			rejected = append(rejected, service.JobRowError{Row: result.Index, Email: result.Email, Status: result.Status, Error: result.Error})
+		}
+	}
+	return h.jobs.AppendRows(c.Request.Context(), jobID, rows, rejected)
+}
+
+// startJob mengantrekan job dan menulis respons 202 berisi lokasi status job.
+func (h *InvitationHandler) startJob(c *gin.Context, job *service.Job) {
+	if err := h.jobs.StartJob(c.Request.Context(), job.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal mengantrekan job undangan"})
+		return
+	}
+	statusURL := "/invitations/jobs/" + job.ID
+	c.Header("Location", statusURL)
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id":     job.ID,
+		"status":     service.JobStatusQueued,
+		"status_url": statusURL,
+	})
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// bulkChunkSize adalah jumlah undangan yang ditulis ke Redis dalam satu transaksi.
+const bulkChunkSize = 100
+
+// BulkCreateResult adalah hasil pembuatan satu undangan pada CreateInvitations. Tepat
+// salah satu dari Invitation atau Err terisi.
+type BulkCreateResult struct {
+	Invitation *InvitationData
+	Err        error
+}
+
+// NormalizeEmail menyeragamkan alamat email untuk perbandingan, misalnya saat mendeteksi
+// undangan ganda. Email yang disimpan tetap sesuai masukan pengguna.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// CreateInvitations membuat banyak undangan sekaligus. Undangan ditulis ke Redis per
+// kelompok dalam satu transaksi pipeline, dan email dikirim berurutan setelah setiap
+// kelompok tersimpan. Hasil dikembalikan sesuai urutan reqs; kegagalan satu kelompok
+// tidak membatalkan kelompok lainnya. Baris yang emailnya sudah memiliki undangan aktif
+// menghasilkan DuplicateInvitationError, kecuali Upsert diaktifkan pada baris tersebut.
+// Setiap baris juga melalui validasi role, batas laju dan kuota kursi yang sama dengan
+// CreateInvitation.
+func (s *invitationService) CreateInvitations(ctx context.Context, reqs []CreateInvitationRequest) []BulkCreateResult {
+	results := make([]BulkCreateResult, len(reqs))
+	for start := 0; start < len(reqs); start += bulkChunkSize {
+		end := min(start+bulkChunkSize, len(reqs))
+		s.createChunk(ctx, reqs[start:end], results[start:end])
+	}
+	return results
+}
+
+func (s *invitationService) createChunk(ctx context.Context, reqs []CreateInvitationRequest, results []BulkCreateResult) {
+	emailKeys := make([]string, len(reqs))
+	tenantIDs := make([]string, len(reqs))
+	rejected := make([]error, len(reqs))
+	for i, req := range reqs {
+		emailKeys[i] = emailIndexKey(req.TenantID, req.Email)
+		tenantIDs[i] = req.TenantID
+		rejected[i] = s.admit(ctx, req)
+	}
+
+	quota, err := s.loadSeatQuota(ctx, tenantIDs...)
+	if err != nil {
+		log.Error().Err(err).Int("count", len(reqs)).Msg("Gagal mengambil kuota kursi untuk kelompok undangan massal")
+		for i := range results {
+			results[i] = BulkCreateResult{Err: err}
+		}
+		return
+	}
+
+	var pending []*pendingInvitation
+	txf := func(tx *redis.Tx) error {
+		// Hasil dari percobaan transaksi sebelumnya dibuang.
+		pending = make([]*pendingInvitation, len(reqs))
+		quota.reset()
+		for i := range results {
+			results[i] = BulkCreateResult{}
+		}
+		owners, err := tx.MGet(ctx, emailKeys...).Result()
+		if err != nil {
+			return err
+		}
+
+		// claimed mencegah dua baris dengan email yang sama dalam satu kelompok sama-sama dibuat.
+		claimed := make(map[string]string, len(reqs))
+		for i, req := range reqs {
+			if rejected[i] != nil {
+				results[i].Err = rejected[i]
+				continue
+			}
+			if id, ok := claimed[emailKeys[i]]; ok {
+				results[i].Err = &DuplicateInvitationError{InvitationID: id}
+				continue
+			}
+			ownerID, _ := owners[i].(string)
+			p, err := s.planInvitation(ctx, tx, req, ownerID, quota)
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			pending[i] = p
+			claimed[emailKeys[i]] = p.data.ID
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, p := range pending {
+				if p != nil {
+					p.write(ctx, pipe)
+				}
+			}
+			return nil
+		})
+		return err
+	}
+
+	if err := s.withWatch(ctx, txf, append(quota.watchKeys(), emailKeys...)...); err != nil {
+		log.Error().Err(err).Int("count", len(reqs)).Msg("Gagal menyimpan kelompok undangan massal")
+		// txf mungkin tidak pernah berjalan (misalnya WATCH gagal), sehingga pending masih nil.
+		// Penolakan per baris dari percobaan terakhir tetap dilaporkan, baris lainnya gagal.
+		for i := range results {
+			switch {
+			case rejected[i] != nil:
+				results[i] = BulkCreateResult{Err: rejected[i]}
+			case pending == nil || pending[i] != nil || results[i].Err == nil:
+				results[i] = BulkCreateResult{Err: err}
+			}
+		}
+		return
+	}
+
+	messages := make([]*OutboxMessage, 0, len(pending))
+	for i, p := range pending {
+		if p == nil {
+			continue
+		}
+		results[i].Invitation = &p.data
+		messages = append(messages, p.message)
+	}
+	s.dispatchOutbox(ctx, messages...)
+	for _, p := range pending {
+		if p != nil {
+			s.emitWritten(ctx, p)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvitationService_CreateInvitations(t *testing.T) {
+	ctx := context.Background()
+	mockPublisher := new(MockQueuePublisher)
+	mockPublisher.On("Enqueue", ctx, mock.Anything).Return(nil)
+	svc, mr := newMiniredisService(t, mockPublisher, &UUIDTokenGenerator{})
+
+	reqs := make([]CreateInvitationRequest, bulkChunkSize+5)
+	for i := range reqs {
+		reqs[i] = CreateInvitationRequest{Email: fmt.Sprintf("user-%d@example.com", i), Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"}
+	}
+
+	results := svc.CreateInvitations(ctx, reqs)
+
+	require.Len(t, results, len(reqs))
+	for i, result := range results {
+		require.NoError(t, result.Err)
+		assert.Equal(t, reqs[i].Email, result.Invitation.Email)
+	}
+	indexed, err := mr.ZMembers(tenantIndexKey("tenant-1"))
+	require.NoError(t, err)
+	assert.Len(t, indexed, len(reqs))
+	assert.False(t, mr.Exists(outboxPendingKey), "semua pesan yang terkirim harus dihapus dari outbox")
+	mockPublisher.AssertNumberOfCalls(t, "Enqueue", len(reqs))
+}
+
+func TestInvitationService_CreateInvitationsRedisUnavailable(t *testing.T) {
+	ctx := context.Background()
+	redisClient := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	require.NoError(t, redisClient.Close())
+	mockPublisher := new(MockQueuePublisher)
+	svc := newTestService(redisClient, mockPublisher, &UUIDTokenGenerator{}, 24)
+	WithRoleValidator(NewStaticRoleValidator([]string{"viewer"}))(svc)
+
+	results := svc.CreateInvitations(ctx, []CreateInvitationRequest{
+		{Email: "first@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1", InviterRole: "viewer"},
+		{Email: "second@example.com", Role: "owner", TenantID: "tenant-1", InviterID: "inviter-1", InviterRole: "viewer"},
+	})
+
+	// WATCH gagal sebelum transaksi berjalan; setiap baris tetap harus memiliki error.
+	require.Len(t, results, 2)
+	assert.ErrorIs(t, results[0].Err, redis.ErrClosed)
+	assert.Nil(t, results[0].Invitation)
+	assert.ErrorIs(t, results[1].Err, ErrUnknownRole)
+	assert.Nil(t, results[1].Invitation)
+	mockPublisher.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+}
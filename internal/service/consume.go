@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/client"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// consumeScript membaca dan menghapus token undangan dalam satu operasi atomik, sehingga
+// paling banyak satu pemanggil yang pernah menerima data undangan untuk token yang sama.
+//
+// KEYS[1] = key data undangan, KEYS[2] = key reservasi.
+// ARGV[1] = ID reservasi milik pemanggil, atau string kosong untuk penerimaan langsung.
+var consumeScript = redis.NewScript(`
+local payload = redis.call('GET', KEYS[1])
+local reservation = redis.call('GET', KEYS[2])
+if ARGV[1] ~= '' and reservation ~= ARGV[1] then
+	return {'mismatch'}
+end
+if not payload then
+	return {'invalid'}
+end
+if ARGV[1] == '' and reservation then
+	return {'reserved'}
+end
+redis.call('DEL', KEYS[1], KEYS[2])
+return {'ok', payload}
+`)
+
+// consumeToken mengonsumsi token secara atomik. Jika reservationID tidak kosong, token
+// hanya dikonsumsi bila reservasi aktif adalah milik pemanggil.
+func (s *invitationService) consumeToken(ctx context.Context, tokenHash, reservationID string) (*InvitationData, error) {
+	keys := []string{tokenKey(tokenHash), reservationKey(tokenHash)}
+	result, err := consumeScript.Run(ctx, s.redisClient, keys, reservationID).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengonsumsi token undangan: %w", err)
+	}
+
+	status, _ := result[0].(string)
+	switch status {
+	case "ok":
+	case "invalid":
+		return nil, ErrInvalidToken
+	case "reserved":
+		return nil, ErrInvitationReserved
+	case "mismatch":
+		return nil, ErrReservationMismatch
+	default:
+		return nil, fmt.Errorf("respons skrip konsumsi tidak dikenal: %v", result)
+	}
+
+	payload, _ := result[1].(string)
+	var data InvitationData
+	if err := json.Unmarshal([]byte(payload), &data); err != nil {
+		// Token sudah terhapus; data yang rusak tidak dapat dipulihkan oleh pemanggil lain.
+		log.Error().Err(err).Msg("Data undangan yang dikonsumsi tidak dapat dibaca")
+		return nil, fmt.Errorf("gagal unmarshal data undangan: %w", err)
+	}
+
+	// Token sudah tidak dapat dipakai lagi; pembersihan pointer dan indeks bersifat best effort
+	// dan entri yang tertinggal akan dibersihkan oleh ListInvitations.
+	s.removeFromIndex(ctx, &data)
+	s.emitEvent(ctx, client.EventInvitationAccepted, data.TenantID, &data)
+	return &data, nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"time"
+)
+
+// Error sentinel yang dapat diperiksa oleh handler menggunakan errors.Is
+// untuk menentukan status HTTP yang sesuai.
+var (
+	// ErrInvalidCursor dikembalikan ketika cursor paginasi tidak dapat didekode.
+	ErrInvalidCursor = errors.New("cursor paginasi tidak valid")
+
+	// ErrInvalidToken dikembalikan ketika token undangan tidak dikenal atau sudah kedaluwarsa.
+	ErrInvalidToken = errors.New("undangan tidak valid atau sudah kedaluwarsa")
+
+	// ErrInvitationReserved dikembalikan ketika undangan sedang direservasi oleh proses pendaftaran lain.
+	ErrInvitationReserved = errors.New("undangan sedang diproses oleh pendaftaran lain")
+
+	// ErrReservationMismatch dikembalikan ketika reservasi tidak ada, sudah kedaluwarsa,
+	// atau bukan milik pemanggil.
+	ErrReservationMismatch = errors.New("reservasi undangan tidak valid atau sudah kedaluwarsa")
+
+	// ErrInvitationNotFound dikembalikan ketika undangan dengan ID tertentu tidak ada,
+	// sudah kedaluwarsa, atau milik tenant lain.
+	ErrInvitationNotFound = errors.New("undangan tidak ditemukan")
+
+	// ErrResendLimitReached dikembalikan ketika undangan sudah mencapai batas pengiriman ulang.
+	ErrResendLimitReached = errors.New("batas pengiriman ulang undangan telah tercapai")
+
+	// ErrResendCooldown dikembalikan ketika undangan dikirim ulang sebelum jeda minimum berlalu.
+	ErrResendCooldown = errors.New("undangan baru saja dikirim, silakan coba beberapa saat lagi")
+
+	// ErrJobNotFound dikembalikan ketika job undangan massal tidak ada, sudah kedaluwarsa,
+	// atau milik tenant lain.
+	ErrJobNotFound = errors.New("job undangan tidak ditemukan")
+
+	// ErrDuplicateInvitation dikembalikan ketika tenant sudah memiliki undangan aktif untuk email yang sama.
+	ErrDuplicateInvitation = errors.New("undangan aktif untuk email ini sudah ada")
+
+	// ErrUnknownRole dikembalikan ketika role undangan tidak ada di katalog role tenant.
+	ErrUnknownRole = errors.New("role tidak dikenal di tenant ini")
+
+	// ErrRoleNotGrantable dikembalikan ketika role undangan lebih tinggi dari role pengundang.
+	ErrRoleNotGrantable = errors.New("role undangan tidak boleh melebihi role pengundang")
+
+	// ErrRateLimited dikembalikan ketika batas laju pembuatan undangan terlampaui.
+	ErrRateLimited = errors.New("terlalu banyak undangan dibuat, silakan coba beberapa saat lagi")
+
+	// ErrTooManyAttempts dikembalikan ketika pemanggil dikunci karena terlalu banyak token tidak valid.
+	ErrTooManyAttempts = errors.New("terlalu banyak percobaan token tidak valid, silakan coba beberapa saat lagi")
+
+	// ErrSeatLimitExceeded dikembalikan ketika undangan baru akan melebihi sisa kursi paket tenant.
+	ErrSeatLimitExceeded = errors.New("kuota kursi paket tenant sudah habis")
+
+	// ErrExpiryInPast dikembalikan ketika ExpiresAt undangan tidak berada di masa depan.
+	ErrExpiryInPast = errors.New("expires_at harus berada di masa depan")
+)
+
+// RetryAfterError membungkus error yang dapat dicoba lagi setelah jeda tertentu,
+// sehingga handler dapat mengisi header Retry-After.
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string { return e.Err.Error() }
+
+func (e *RetryAfterError) Unwrap() error { return e.Err }
+
+// DuplicateInvitationError membawa ID undangan aktif yang menyebabkan ErrDuplicateInvitation,
+// sehingga pemanggil dapat merujuk undangan tersebut, misalnya untuk mengirim ulang.
+type DuplicateInvitationError struct {
+	InvitationID string
+	// Replayed menandai undangan yang sudah dibuat oleh permintaan dengan IdempotencyKey yang
+	// sama, sehingga pengulangan permintaan tersebut dapat dianggap berhasil.
+	Replayed bool
+}
+
+func (e *DuplicateInvitationError) Error() string { return ErrDuplicateInvitation.Error() }
+
+func (e *DuplicateInvitationError) Unwrap() error { return ErrDuplicateInvitation }
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/client"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// invitationResentPayload adalah payload event invitation.resent.
+type invitationResentPayload struct {
+	*InvitationData
+	ResentBy string `json:"resentBy"`
+}
+
+// invitationUpdatedPayload adalah payload event invitation.updated, diterbitkan ketika
+// undangan aktif ditimpa melalui upsert.
+type invitationUpdatedPayload struct {
+	*InvitationData
+	PreviousRole string `json:"previousRole"`
+}
+
+// invitationExpiredPayload adalah payload event invitation.expired. Data undangan sudah
+// dihapus oleh TTL Redis saat event ini diterbitkan, sehingga hanya ID yang tersedia.
+type invitationExpiredPayload struct {
+	InvitationID string `json:"invitationID"`
+}
+
+// WithEventPublisher mengaktifkan penerbitan domain event siklus hidup undangan.
+func WithEventPublisher(publisher client.EventPublisher) Option {
+	return func(s *invitationService) {
+		s.eventPublisher = publisher
+	}
+}
+
+// emitEvent menerbitkan domain event setelah perubahan state tersimpan di Redis.
+func (s *invitationService) emitEvent(ctx context.Context, eventType, tenantID string, payload interface{}) {
+	publishEvent(ctx, s.eventPublisher, s.now(), eventType, tenantID, payload)
+}
+
+// publishEvent bersifat best effort: kegagalan dicatat di log dan tidak membatalkan
+// operasi yang sudah berhasil disimpan.
+func publishEvent(ctx context.Context, publisher client.EventPublisher, occurredAt time.Time, eventType, tenantID string, payload interface{}) {
+	if publisher == nil {
+		return
+	}
+	event := client.DomainEvent{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		Version:    client.EventSchemaVersion,
+		TenantID:   tenantID,
+		OccurredAt: occurredAt.UTC(),
+		Payload:    payload,
+	}
+	if err := publisher.Publish(ctx, event); err != nil {
+		log.Error().Err(err).Str("event_type", eventType).Str("tenant_id", tenantID).Msg("Gagal menerbitkan domain event undangan")
+	}
+}
+
+// reapExpired menghapus entri indeks tenant yang undangannya sudah kedaluwarsa dan
+// menerbitkan invitation.expired hanya untuk entri yang benar-benar dihapus oleh pemanggil
+// ini, sehingga beberapa replika yang membersihkan indeks bersamaan tidak menerbitkan event ganda.
+func reapExpired(ctx context.Context, rdb *redis.Client, publisher client.EventPublisher, now time.Time, tenantID string, invitationIDs []string) int {
+	if len(invitationIDs) == 0 {
+		return 0
+	}
+
+	indexKey := tenantIndexKey(tenantID)
+	cmds := make([]*redis.IntCmd, len(invitationIDs))
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range invitationIDs {
+			cmds[i] = pipe.ZRem(ctx, indexKey, id)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("index", indexKey).Msg("Gagal membersihkan indeks undangan yang kedaluwarsa")
+		return 0
+	}
+
+	reaped := 0
+	for i, cmd := range cmds {
+		if cmd.Val() == 1 {
+			reaped++
+			publishEvent(ctx, publisher, now, client.EventInvitationExpired, tenantID, invitationExpiredPayload{InvitationID: invitationIDs[i]})
+		}
+	}
+	return reaped
+}
+
+// DefaultExpirySweepInterval dipakai jika ExpirySweeper dibuat tanpa interval.
+const DefaultExpirySweepInterval = 5 * time.Minute
+
+// ExpirySweeper secara berkala memindai indeks semua tenant dan menerbitkan
+// invitation.expired untuk undangan yang kedaluwarsa tanpa pernah diterima atau dicabut.
+type ExpirySweeper struct {
+	redisClient *redis.Client
+	publisher   client.EventPublisher
+	interval    time.Duration
+}
+
+// NewExpirySweeper membuat sweeper yang berjalan setiap interval.
+func NewExpirySweeper(redisClient *redis.Client, publisher client.EventPublisher, interval time.Duration) *ExpirySweeper {
+	if interval <= 0 {
+		interval = DefaultExpirySweepInterval
+	}
+	return &ExpirySweeper{redisClient: redisClient, publisher: publisher, interval: interval}
+}
+
+// Run menjalankan sweeper hingga ctx dibatalkan.
+func (w *ExpirySweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if reaped, err := w.Sweep(ctx); err != nil {
+				log.Error().Err(err).Msg("Gagal memindai undangan yang kedaluwarsa")
+			} else if reaped > 0 {
+				log.Info().Int("count", reaped).Msg("Undangan kedaluwarsa dibersihkan dari indeks")
+			}
+		}
+	}
+}
+
+// Sweep melakukan satu kali pemindaian dan mengembalikan jumlah undangan kedaluwarsa yang dibersihkan.
+func (w *ExpirySweeper) Sweep(ctx context.Context) (int, error) {
+	reaped := 0
+	prefix := tenantIndexKey("")
+	iter := w.redisClient.Scan(ctx, 0, prefix+"*", listBatchSize).Iterator()
+	for iter.Next(ctx) {
+		indexKey := iter.Val()
+		n, err := w.sweepIndex(ctx, strings.TrimPrefix(indexKey, prefix), indexKey)
+		if err != nil {
+			return reaped, err
+		}
+		reaped += n
+	}
+	return reaped, iter.Err()
+}
+
+func (w *ExpirySweeper) sweepIndex(ctx context.Context, tenantID, indexKey string) (int, error) {
+	reaped := 0
+	var cursor uint64
+	for {
+		// ZSCAN mengembalikan pasangan member dan skor secara bergantian.
+		pairs, next, err := w.redisClient.ZScan(ctx, indexKey, cursor, "", listBatchSize).Result()
+		if err != nil {
+			return reaped, err
+		}
+
+		ids := make([]string, 0, len(pairs)/2)
+		idKeys := make([]string, 0, len(pairs)/2)
+		for i := 0; i < len(pairs); i += 2 {
+			ids = append(ids, pairs[i])
+			idKeys = append(idKeys, invitationIDKey(pairs[i]))
+		}
+		if len(idKeys) > 0 {
+			pointers, err := w.redisClient.MGet(ctx, idKeys...).Result()
+			if err != nil {
+				return reaped, err
+			}
+			var expired []string
+			for i, pointer := range pointers {
+				if pointer == nil {
+					expired = append(expired, ids[i])
+				}
+			}
+			reaped += reapExpired(ctx, w.redisClient, w.publisher, time.Now(), tenantID, expired)
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return reaped, nil
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvitationService_DomainEvents(t *testing.T) {
+	ctx := context.Background()
+	t.Run("Created, Resent And Accepted", func(t *testing.T) {
+		events := &MockEventPublisher{}
+		tokenGen := &MockTokenGenerator{TokenToReturn: "event-token"}
+		svc, _ := newDeliveringService(t, tokenGen)
+		svc.eventPublisher = events
+		svc.resendCooldown = 0
+
+		invitation, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "user@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+		tokenGen.TokenToReturn = "event-token-2"
+		_, err = svc.ResendInvitation(ctx, "tenant-1", invitation.ID, "admin-1")
+		require.NoError(t, err)
+		_, err = svc.ValidateInvitation(ctx, "event-token-2")
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{client.EventInvitationCreated, client.EventInvitationResent, client.EventInvitationAccepted}, events.Types())
+		for _, event := range events.events {
+			assert.Equal(t, "tenant-1", event.TenantID)
+			assert.Equal(t, client.EventSchemaVersion, event.Version)
+			assert.NotEmpty(t, event.ID)
+		}
+	})
+
+	t.Run("Revoked", func(t *testing.T) {
+		events := &MockEventPublisher{}
+		svc, _ := newDeliveringService(t, &MockTokenGenerator{TokenToReturn: "revoked-event-token"})
+		svc.eventPublisher = events
+
+		invitation, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "user@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+		_, err = svc.RevokeInvitation(ctx, "tenant-1", invitation.ID, "admin-1")
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{client.EventInvitationCreated, client.EventInvitationRevoked}, events.Types())
+	})
+
+	t.Run("Expired Once", func(t *testing.T) {
+		events := &MockEventPublisher{}
+		svc, mr := newDeliveringService(t, &MockTokenGenerator{TokenToReturn: "expired-event-token"})
+
+		invitation, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "user@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+		mr.FastForward(svc.ttl + time.Second)
+
+		sweeper := NewExpirySweeper(svc.redisClient, events, time.Minute)
+		reaped, err := sweeper.Sweep(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, reaped)
+
+		reaped, err = sweeper.Sweep(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, reaped)
+
+		require.Equal(t, []string{client.EventInvitationExpired}, events.Types())
+		assert.Equal(t, invitationExpiredPayload{InvitationID: invitation.ID}, events.events[0].Payload)
+	})
+
+	t.Run("Default Sweep Interval", func(t *testing.T) {
+		sweeper := NewExpirySweeper(nil, &MockEventPublisher{}, 0)
+		assert.Equal(t, DefaultExpirySweepInterval, sweeper.interval)
+	})
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExpiryBounds adalah batas masa berlaku undangan yang boleh dipilih pengundang.
+type ExpiryBounds struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// DefaultExpiryBounds dipakai untuk tenant yang tidak memiliki batas sendiri jika
+// WithExpiryBounds tidak diberikan.
+var DefaultExpiryBounds = ExpiryBounds{Min: time.Hour, Max: 30 * 24 * time.Hour}
+
+// WithExpiryBounds mengatur batas masa berlaku undangan untuk semua tenant beserta
+// pengecualian per tenant.
+func WithExpiryBounds(defaults ExpiryBounds, tenants map[string]ExpiryBounds) Option {
+	return func(s *invitationService) {
+		s.expiryBounds = defaults
+		s.tenantExpiryBounds = tenants
+	}
+}
+
+// Validate memastikan batas valid dan memuat defaultTTL, TTL global untuk undangan tanpa masa
+// berlaku pilihan pengundang. Tanpa pemeriksaan ini TTL di luar batas akan dijepit diam-diam.
+func (b ExpiryBounds) Validate(defaultTTL time.Duration) error {
+	if b.Min <= 0 || b.Max < b.Min {
+		return fmt.Errorf("batas masa berlaku undangan tidak valid: minimum %s, maksimum %s", b.Min, b.Max)
+	}
+	if defaultTTL < b.Min || defaultTTL > b.Max {
+		return fmt.Errorf("masa berlaku undangan default %s berada di luar batas %s sampai %s", defaultTTL, b.Min, b.Max)
+	}
+	return nil
+}
+
+// ParseExpiryBounds membaca batas masa berlaku per tenant dalam format jam
+// "tenant-a=24:168,tenant-b=1:72", yaitu minimum dan maksimum dipisah titik dua.
+func ParseExpiryBounds(overrides string) (map[string]ExpiryBounds, error) {
+	bounds := make(map[string]ExpiryBounds)
+	for _, entry := range strings.Split(overrides, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		tenantID, value, hasValue := strings.Cut(entry, "=")
+		minHours, maxHours, hasRange := strings.Cut(value, ":")
+		minValue, errMin := strconv.Atoi(strings.TrimSpace(minHours))
+		maxValue, errMax := strconv.Atoi(strings.TrimSpace(maxHours))
+		if !hasValue || !hasRange || errMin != nil || errMax != nil || minValue <= 0 || maxValue < minValue {
+			return nil, fmt.Errorf("format batas masa berlaku undangan tidak valid: %q", entry)
+		}
+		bounds[strings.TrimSpace(tenantID)] = ExpiryBounds{
+			Min: time.Duration(minValue) * time.Hour,
+			Max: time.Duration(maxValue) * time.Hour,
+		}
+	}
+	return bounds, nil
+}
+
+// lifetimeFor menentukan masa berlaku undangan dari ExpiresAt atau ExpiresIn pada req, atau
+// fallback jika keduanya kosong, lalu menjepitnya ke batas tenant. ExpiresAt yang sudah lewat
+// ditolak lebih dulu oleh admit.
+func (s *invitationService) lifetimeFor(req CreateInvitationRequest, now time.Time, fallback time.Duration) time.Duration {
+	lifetime := fallback
+	switch {
+	case !req.ExpiresAt.IsZero():
+		lifetime = req.ExpiresAt.Sub(now)
+	case req.ExpiresIn > 0:
+		lifetime = req.ExpiresIn
+	}
+
+	bounds, ok := s.tenantExpiryBounds[req.TenantID]
+	if !ok {
+		bounds = s.expiryBounds
+	}
+	return min(max(lifetime, bounds.Min), bounds.Max)
+}
+
+// lifetime mengembalikan masa berlaku yang dipilih saat undangan dibuat. Undangan lama yang
+// belum menyimpannya memakai TTL global.
+func (d *InvitationData) lifetime(fallback time.Duration) time.Duration {
+	if d.LifetimeSeconds <= 0 {
+		return fallback
+	}
+	return time.Duration(d.LifetimeSeconds) * time.Second
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvitationService_CustomExpiry(t *testing.T) {
+	ctx := context.Background()
+	newReq := func(tenantID, email string) CreateInvitationRequest {
+		return CreateInvitationRequest{Email: email, Role: "viewer", TenantID: tenantID, InviterID: "inviter-1"}
+	}
+
+	t.Run("Expires In Within Bounds", func(t *testing.T) {
+		mockPublisher := new(MockQueuePublisher)
+		mockPublisher.On("Enqueue", ctx, mock.MatchedBy(func(p client.NotificationPayload) bool {
+			return p.TemplateData["ExpiresAt"] == "2025-01-03T03:04:05Z"
+		})).Return(nil).Once()
+		svc, mr := newMiniredisService(t, mockPublisher, &UUIDTokenGenerator{})
+
+		req := newReq("tenant-1", "contractor@example.com")
+		req.ExpiresIn = 24 * time.Hour
+		invitation, err := svc.CreateInvitation(ctx, req)
+
+		require.NoError(t, err)
+		assert.Equal(t, fixedNow.Add(24*time.Hour), invitation.ExpiresAt)
+		assert.Equal(t, 24*time.Hour, mr.TTL(invitationIDKey(invitation.ID)))
+		mockPublisher.AssertExpectations(t)
+	})
+
+	t.Run("Clamped To Tenant Bounds", func(t *testing.T) {
+		svc, _ := newDeliveringService(t, &UUIDTokenGenerator{})
+		tenantBounds, err := ParseExpiryBounds("tenant-2=24:72")
+		require.NoError(t, err)
+		WithExpiryBounds(ExpiryBounds{Min: time.Hour, Max: 30 * 24 * time.Hour}, tenantBounds)(svc)
+
+		long := newReq("tenant-1", "executive@example.com")
+		long.ExpiresAt = fixedNow.Add(90 * 24 * time.Hour)
+		invitation, err := svc.CreateInvitation(ctx, long)
+		require.NoError(t, err)
+		assert.Equal(t, fixedNow.Add(30*24*time.Hour), invitation.ExpiresAt)
+
+		short := newReq("tenant-2", "contractor@example.com")
+		short.ExpiresIn = time.Hour
+		invitation, err = svc.CreateInvitation(ctx, short)
+		require.NoError(t, err)
+		assert.Equal(t, fixedNow.Add(24*time.Hour), invitation.ExpiresAt)
+
+		// Tanpa masa berlaku pilihan, TTL global (24 jam) dipakai karena masih dalam batas tenant.
+		invitation, err = svc.CreateInvitation(ctx, newReq("tenant-2", "default@example.com"))
+		require.NoError(t, err)
+		assert.Equal(t, fixedNow.Add(svc.ttl), invitation.ExpiresAt)
+	})
+
+	t.Run("Expires At In The Past Rejected", func(t *testing.T) {
+		svc, mr := newDeliveringService(t, &UUIDTokenGenerator{})
+
+		for _, expiresAt := range []time.Time{fixedNow.Add(-time.Hour), fixedNow} {
+			req := newReq("tenant-1", "late@example.com")
+			req.ExpiresAt = expiresAt
+			_, err := svc.CreateInvitation(ctx, req)
+			assert.ErrorIs(t, err, ErrExpiryInPast)
+		}
+		assert.False(t, mr.Exists(emailIndexKey("tenant-1", "late@example.com")))
+	})
+
+	t.Run("Resend Reuses Chosen Lifetime", func(t *testing.T) {
+		svc, mr := newDeliveringService(t, &UUIDTokenGenerator{})
+		svc.resendCooldown = time.Minute
+
+		req := newReq("tenant-1", "executive@example.com")
+		req.ExpiresIn = 30 * 24 * time.Hour
+		invitation, err := svc.CreateInvitation(ctx, req)
+		require.NoError(t, err)
+
+		later := fixedNow.Add(time.Hour)
+		svc.now = func() time.Time { return later }
+		resent, err := svc.ResendInvitation(ctx, "tenant-1", invitation.ID, "admin-1")
+
+		require.NoError(t, err)
+		assert.Equal(t, later.Add(30*24*time.Hour), resent.ExpiresAt)
+		assert.Equal(t, 30*24*time.Hour, mr.TTL(invitationIDKey(invitation.ID)))
+	})
+
+	t.Run("Invalid Bounds", func(t *testing.T) {
+		_, err := ParseExpiryBounds("tenant-1=72:24")
+		assert.Error(t, err)
+		_, err = ParseExpiryBounds("tenant-1=24")
+		assert.Error(t, err)
+	})
+
+	t.Run("Default TTL Outside Bounds", func(t *testing.T) {
+		bounds := ExpiryBounds{Min: time.Hour, Max: 72 * time.Hour}
+		assert.NoError(t, bounds.Validate(72*time.Hour))
+		assert.ErrorContains(t, bounds.Validate(168*time.Hour), "di luar batas")
+		assert.Error(t, ExpiryBounds{Min: 72 * time.Hour, Max: time.Hour}.Validate(24*time.Hour))
+	})
+}
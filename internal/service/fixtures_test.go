@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/client"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- Mocks dan fixture bersama untuk test service ---
+
+// Mock untuk QueuePublisher (menggantikan MockNotificationClient)
+type MockQueuePublisher struct {
+	mock.Mock
+}
+
+// Implementasi interface QueuePublisher
+func (m *MockQueuePublisher) Enqueue(ctx context.Context, payload client.NotificationPayload) error {
+	args := m.Called(ctx, payload)
+	return args.Error(0)
+}
+
+func (m *MockQueuePublisher) Close() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+// Pastikan mock memenuhi interface.
+var _ client.QueuePublisher = (*MockQueuePublisher)(nil)
+
+// MockTokenGenerator tetap sama.
+type MockTokenGenerator struct {
+	TokenToReturn string
+}
+
+func (m *MockTokenGenerator) Generate(TokenClaims) (string, error) {
+	if m.TokenToReturn != "" {
+		return m.TokenToReturn, nil
+	}
+	return "fixed-mock-token", nil
+}
+
+var _ TokenGenerator = (*MockTokenGenerator)(nil)
+
+// fixedNow digunakan sebagai jam service agar payload yang ditulis ke Redis dapat diprediksi.
+var fixedNow = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+const fixedInvitationID = "inv-fixed-id"
+
+func newTestService(redisClient *redis.Client, publisher client.QueuePublisher, tokenGen TokenGenerator, ttlHours int) *invitationService {
+	svc := NewInvitationService(redisClient, publisher, tokenGen, ttlHours).(*invitationService)
+	svc.now = func() time.Time { return fixedNow }
+	svc.newID = func() string { return fixedInvitationID }
+	return svc
+}
+
+// newMiniredisService menjalankan service terhadap Redis in-memory untuk skenario yang
+// membutuhkan semantik transaksi (WATCH/MULTI) atau skrip Lua yang sebenarnya.
+func newMiniredisService(t *testing.T, publisher client.QueuePublisher, tokenGen TokenGenerator) (*invitationService, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = redisClient.Close() })
+
+	svc := NewInvitationService(redisClient, publisher, tokenGen, 24).(*invitationService)
+	svc.now = func() time.Time { return fixedNow }
+	return svc, mr
+}
+
+// MockEventPublisher merekam domain event yang diterbitkan service.
+type MockEventPublisher struct {
+	mu     sync.Mutex
+	events []client.DomainEvent
+}
+
+func (m *MockEventPublisher) Publish(ctx context.Context, event client.DomainEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *MockEventPublisher) Close() error { return nil }
+
+func (m *MockEventPublisher) Types() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	types := make([]string, len(m.events))
+	for i, event := range m.events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+var _ client.EventPublisher = (*MockEventPublisher)(nil)
+
+// newDeliveringService adalah newMiniredisService dengan publisher yang menerima semua email
+// undangan, untuk skenario yang tidak memeriksa notifikasi.
+func newDeliveringService(t *testing.T, tokenGen TokenGenerator) (*invitationService, *miniredis.Miniredis) {
+	t.Helper()
+	publisher := new(MockQueuePublisher)
+	publisher.On("Enqueue", mock.Anything, mock.Anything).Return(nil)
+	return newMiniredisService(t, publisher, tokenGen)
+}
@@ -5,83 +5,489 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/client"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	// DefaultListLimit dan MaxListLimit mengatur ukuran halaman untuk ListInvitations.
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+
+	// listBatchSize adalah jumlah entri indeks yang dibaca dari Redis per iterasi.
+	listBatchSize = 100
+
+	// revocationRetention adalah lama catatan pencabutan disimpan untuk keperluan audit.
+	revocationRetention = 30 * 24 * time.Hour
+
+	// maxTxRetries membatasi percobaan ulang transaksi WATCH yang gagal karena perubahan bersamaan.
+	maxTxRetries = 3
+
+	// DefaultMaxResends dan DefaultResendCooldown dipakai jika WithResendPolicy tidak diberikan.
+	DefaultMaxResends     = 3
+	DefaultResendCooldown = 10 * time.Minute
+
+	// DefaultReservationLease adalah lama reservasi undangan berlaku sebelum dilepas otomatis.
+	DefaultReservationLease = 5 * time.Minute
+)
+
 type InvitationData struct {
-	Email    string `json:"email"`
-	Role     string `json:"role"`
-	TenantID string `json:"tenantID"`
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	TenantID  string    `json:"tenantID"`
+	InviterID string    `json:"inviterID"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// LifetimeSeconds adalah masa berlaku yang dipilih saat undangan dibuat, dipakai kembali
+	// saat undangan dikirim ulang.
+	LifetimeSeconds int64 `json:"lifetimeSeconds,omitempty"`
+	// ResendCount dan LastSentAt dipakai untuk menegakkan batas dan jeda pengiriman ulang.
+	ResendCount int       `json:"resendCount"`
+	LastSentAt  time.Time `json:"lastSentAt"`
+	// Metadata audit dari permintaan pembuatan undangan.
+	SourceIP  string `json:"sourceIP"`
+	UserAgent string `json:"userAgent"`
+	Message   string `json:"message,omitempty"`
+	// Name dan Locale opsional dipakai untuk personalisasi email undangan.
+	Name   string `json:"name,omitempty"`
+	Locale string `json:"locale,omitempty"`
+	// ShortCode menandai undangan yang tokennya kode pendek; pengiriman ulang memakai kode baru.
+	ShortCode bool `json:"shortCode,omitempty"`
+	// UpdatedAt diisi ketika undangan yang sudah ada diperbarui melalui upsert.
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	// IdempotencyKey menyimpan CreateInvitationRequest.IdempotencyKey permintaan yang terakhir
+	// menulis undangan ini.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+}
+
+// CreateInvitationRequest berisi masukan untuk membuat undangan baru, termasuk
+// metadata audit yang disimpan bersama undangan dan dikembalikan saat validasi.
+type CreateInvitationRequest struct {
+	Email     string
+	Role      string
+	TenantID  string
+	InviterID string
+	// InviterRole adalah role pengundang dari token, dipakai RoleValidator untuk memastikan
+	// pengundang tidak memberikan role yang lebih tinggi dari miliknya.
+	InviterRole string
+	SourceIP    string
+	UserAgent   string
+	// Message adalah pesan opsional dari pengundang yang disertakan di email undangan.
+	Message string
+	// Name dan Locale opsional adalah nama penerima dan bahasa email undangan.
+	Name   string
+	Locale string
+	// Upsert memperbarui undangan aktif untuk email yang sama dengan token baru, alih-alih
+	// mengembalikan DuplicateInvitationError.
+	Upsert bool
+	// ExpiresAt atau ExpiresIn opsional menentukan masa berlaku undangan; ExpiresAt diutamakan
+	// jika keduanya diisi. Nilainya dijepit ke batas masa berlaku tenant, dan TTL global
+	// dipakai jika keduanya kosong. ExpiresAt yang sudah lewat ditolak dengan ErrExpiryInPast.
+	ExpiresAt time.Time
+	ExpiresIn time.Duration
+	// ShortCode meminta kode pendek yang dapat diketik, misalnya "K7QF-9M2X", sebagai pengganti
+	// token panjang. Membutuhkan WithShortCodes.
+	ShortCode bool
+	// IdempotencyKey opsional menandai permintaan yang dapat diulang, misalnya baris job massal.
+	// Jika undangan aktif untuk email yang sama ditulis dengan key yang sama, permintaan dianggap
+	// sudah berhasil dan DuplicateInvitationError dengan Replayed dikembalikan tanpa menulis ulang.
+	IdempotencyKey string
+}
+
+// ListFilter menampung parameter filter dan paginasi untuk ListInvitations.
+type ListFilter struct {
+	Role        string
+	EmailPrefix string
+	Cursor      string
+	Limit       int
+}
+
+// InvitationPage adalah satu halaman hasil ListInvitations.
+// NextCursor kosong jika tidak ada halaman berikutnya.
+type InvitationPage struct {
+	Invitations []InvitationData `json:"data"`
+	NextCursor  string           `json:"next_cursor,omitempty"`
+}
+
+// RevocationRecord mencatat siapa yang mencabut sebuah undangan dan kapan.
+type RevocationRecord struct {
+	InvitationID string    `json:"invitationID"`
+	TenantID     string    `json:"tenantID"`
+	Email        string    `json:"email"`
+	RevokedBy    string    `json:"revokedBy"`
+	RevokedAt    time.Time `json:"revokedAt"`
 }
 
 type InvitationService interface {
-	CreateInvitation(ctx context.Context, email, role, tenantID, inviterID string) (string, error)
+	CreateInvitation(ctx context.Context, req CreateInvitationRequest) (*InvitationData, error)
+	CreateInvitations(ctx context.Context, reqs []CreateInvitationRequest) []BulkCreateResult
 	ValidateInvitation(ctx context.Context, token string) (*InvitationData, error)
+	ListInvitations(ctx context.Context, tenantID string, filter ListFilter) (*InvitationPage, error)
+	RevokeInvitation(ctx context.Context, tenantID, invitationID, revokedBy string) (*RevocationRecord, error)
+	ResendInvitation(ctx context.Context, tenantID, invitationID, resentBy string) (*InvitationData, error)
+	PreviewInvitation(ctx context.Context, token string) (*InvitationData, error)
+	ReserveInvitation(ctx context.Context, token string) (*Reservation, error)
+	CommitInvitation(ctx context.Context, token, reservationID string) (*InvitationData, error)
+	ReleaseInvitation(ctx context.Context, token, reservationID string) error
 }
 
 type invitationService struct {
 	redisClient    *redis.Client
 	queuePublisher client.QueuePublisher
+	eventPublisher client.EventPublisher
 	tokenGenerator TokenGenerator
 	ttl            time.Duration
+	maxResends     int
+	resendCooldown time.Duration
+	leaseDuration  time.Duration
+	roleValidator  RoleValidator
+	rateLimits     RateLimits
+	seatProvider   SeatProvider
+	shortCodes     *ShortCodeGenerator
+	links          *LinkTemplates
+	// peppers mengaktifkan hash token HMAC; acceptLegacyHash tetap mencari hash sha256 lama.
+	peppers          Keyring
+	acceptLegacyHash bool
+	// expiryBounds berlaku untuk tenant yang tidak memiliki entri di tenantExpiryBounds.
+	expiryBounds       ExpiryBounds
+	tenantExpiryBounds map[string]ExpiryBounds
+	now                func() time.Time
+	newID              func() string
 }
 
-func NewInvitationService(redisClient *redis.Client, publisher client.QueuePublisher, tokenGen TokenGenerator, ttlHours int) InvitationService {
-	return &invitationService{
+// Option mengonfigurasi perilaku opsional dari InvitationService.
+type Option func(*invitationService)
+
+// WithResendPolicy mengatur berapa kali sebuah undangan boleh dikirim ulang
+// dan jeda minimum di antara dua pengiriman.
+func WithResendPolicy(maxResends int, cooldown time.Duration) Option {
+	return func(s *invitationService) {
+		s.maxResends = maxResends
+		s.resendCooldown = cooldown
+	}
+}
+
+// WithReservationLease mengatur lama reservasi undangan berlaku pada alur dua fase.
+func WithReservationLease(lease time.Duration) Option {
+	return func(s *invitationService) {
+		s.leaseDuration = lease
+	}
+}
+
+func NewInvitationService(redisClient *redis.Client, publisher client.QueuePublisher, tokenGen TokenGenerator, ttlHours int, opts ...Option) InvitationService {
+	s := &invitationService{
 		redisClient:    redisClient,
 		queuePublisher: publisher,
 		tokenGenerator: tokenGen,
 		ttl:            time.Hour * time.Duration(ttlHours),
+		maxResends:     DefaultMaxResends,
+		resendCooldown: DefaultResendCooldown,
+		leaseDuration:  DefaultReservationLease,
+		expiryBounds:   DefaultExpiryBounds,
+		links:          &LinkTemplates{defaultTemplate: DefaultLinkTemplate},
+		now:            time.Now,
+		newID:          uuid.NewString,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-func (s *invitationService) CreateInvitation(ctx context.Context, email, role, tenantID, inviterID string) (string, error) {
-	token := s.tokenGenerator.Generate()
+// hashToken menghasilkan hash sha256 token tanpa pepper, dipakai jika WithTokenPepper tidak
+// diberikan dan untuk undangan lama yang disimpan sebelum pepper diaktifkan. Token mentah
+// tidak pernah disimpan.
+func hashToken(token string) string {
 	hash := sha256.Sum256([]byte(token))
-	tokenHash := base64.StdEncoding.EncodeToString(hash[:])
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func tokenKey(tokenHash string) string {
+	return fmt.Sprintf("invitation:%s", tokenHash)
+}
+
+// invitationIDKey memetakan ID undangan yang stabil ke hash token yang sedang berlaku.
+func invitationIDKey(invitationID string) string {
+	return fmt.Sprintf("invitation_id:%s", invitationID)
+}
+
+// tenantIndexKey adalah sorted set per tenant berisi ID undangan aktif,
+// dengan skor berupa waktu pembuatan (Unix milidetik).
+func tenantIndexKey(tenantID string) string {
+	return fmt.Sprintf("invitation_index:%s", tenantID)
+}
+
+func revocationKey(invitationID string) string {
+	return fmt.Sprintf("invitation_revocation:%s", invitationID)
+}
+
+// emailIndexKey memetakan email yang dinormalisasi ke ID undangan aktif di sebuah tenant,
+// sehingga satu alamat email paling banyak memiliki satu undangan aktif per tenant.
+func emailIndexKey(tenantID, email string) string {
+	return fmt.Sprintf("invitation_email:%s:%s", tenantID, NormalizeEmail(email))
+}
+
+// CreateInvitation membuat undangan baru setelah role dan batas laju diperiksa. Jika tenant
+// sudah memiliki undangan aktif untuk email yang sama, DuplicateInvitationError dikembalikan;
+// dengan req.Upsert, undangan tersebut diperbarui dengan token baru dan UpdatedAt terisi.
+func (s *invitationService) CreateInvitation(ctx context.Context, req CreateInvitationRequest) (*InvitationData, error) {
+	if err := s.admit(ctx, req); err != nil {
+		return nil, err
+	}
+
+	quota, err := s.loadSeatQuota(ctx, req.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	emailKey := emailIndexKey(req.TenantID, req.Email)
+	var pending *pendingInvitation
+	txf := func(tx *redis.Tx) error {
+		quota.reset()
+		ownerID, err := tx.Get(ctx, emailKey).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if pending, err = s.planInvitation(ctx, tx, req, ownerID, quota); err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pending.write(ctx, pipe)
+			return nil
+		})
+		return err
+	}
+
+	if err := s.withWatch(ctx, txf, append(quota.watchKeys(), emailKey)...); err != nil {
+		return nil, err
+	}
+
+	s.dispatchOutbox(ctx, pending.message)
+	s.emitWritten(ctx, pending)
+
+	return &pending.data, nil
+}
+
+// admit menjalankan pemeriksaan yang harus lolos sebelum undangan ditulis: ketersediaan kode
+// pendek, validasi role, lalu batas laju. Percobaan dengan role yang ditolak tidak
+// menghabiskan kuota.
+func (s *invitationService) admit(ctx context.Context, req CreateInvitationRequest) error {
+	if req.ShortCode && s.shortCodes == nil {
+		return ErrShortCodesDisabled
+	}
+	if !req.ExpiresAt.IsZero() && !req.ExpiresAt.After(s.now()) {
+		return ErrExpiryInPast
+	}
+	if err := s.validateRole(ctx, req); err != nil {
+		return err
+	}
+	return s.checkRateLimit(ctx, req)
+}
 
-	redisKey := fmt.Sprintf("invitation:%s", tokenHash)
-	invitationData := InvitationData{Email: email, Role: role, TenantID: tenantID}
-	payload, err := json.Marshal(invitationData)
+// pendingInvitation adalah undangan yang sudah disiapkan dan siap ditulis ke Redis.
+type pendingInvitation struct {
+	data           InvitationData
+	tokenHash      string
+	payload        []byte
+	message        *OutboxMessage
+	encodedMessage []byte
+	// replacedHash dan previous terisi ketika undangan yang sudah ada diganti tokennya.
+	replacedHash string
+	previous     *InvitationData
+}
+
+// planInvitation menentukan apa yang ditulis untuk req berdasarkan pemilik indeks email saat
+// ini. Pemilik yang undangannya sudah diterima, dicabut, atau kedaluwarsa diabaikan. Undangan
+// baru memakai satu kursi dari quota, sedangkan upsert memakai kursi undangan yang diganti.
+func (s *invitationService) planInvitation(ctx context.Context, tx *redis.Tx, req CreateInvitationRequest, ownerID string, quota *seatQuota) (*pendingInvitation, error) {
+	if ownerID == "" {
+		return s.prepareNew(ctx, tx, req, quota)
+	}
+	oldHash, existing, err := loadInvitationByID(ctx, tx, req.TenantID, ownerID)
+	switch {
+	case errors.Is(err, ErrInvitationNotFound):
+		return s.prepareNew(ctx, tx, req, quota)
+	case err != nil:
+		return nil, err
+	case req.IdempotencyKey != "" && existing.IdempotencyKey == req.IdempotencyKey:
+		return nil, &DuplicateInvitationError{InvitationID: ownerID, Replayed: true}
+	case !req.Upsert:
+		return nil, &DuplicateInvitationError{InvitationID: ownerID}
+	}
+	// Pastikan undangan lama tidak dikonsumsi atau diubah sebelum transaksi dijalankan.
+	if err := tx.Watch(ctx, invitationIDKey(ownerID), tokenKey(oldHash)).Err(); err != nil {
+		return nil, err
+	}
+	return s.prepareUpsert(ctx, tx, oldHash, existing, req)
+}
+
+// prepareNew memakai satu kursi tenant lalu menyiapkan undangan baru.
+func (s *invitationService) prepareNew(ctx context.Context, tx *redis.Tx, req CreateInvitationRequest, quota *seatQuota) (*pendingInvitation, error) {
+	if err := quota.take(ctx, tx, req.TenantID); err != nil {
+		return nil, err
+	}
+	return s.prepareInvitation(ctx, tx, req)
+}
+
+// prepareInvitation membuat token baru dan menyusun data undangan beserta notifikasinya.
+func (s *invitationService) prepareInvitation(ctx context.Context, tx *redis.Tx, req CreateInvitationRequest) (*pendingInvitation, error) {
+	now := s.now().UTC()
+	lifetime := s.lifetimeFor(req, now, s.ttl)
+	return s.preparePending(ctx, tx, InvitationData{
+		ID:              s.newID(),
+		Email:           req.Email,
+		Role:            req.Role,
+		TenantID:        req.TenantID,
+		InviterID:       req.InviterID,
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(lifetime),
+		LifetimeSeconds: int64(lifetime.Seconds()),
+		LastSentAt:      now,
+		SourceIP:        req.SourceIP,
+		UserAgent:       req.UserAgent,
+		Message:         req.Message,
+		Name:            req.Name,
+		Locale:          req.Locale,
+		ShortCode:       req.ShortCode,
+		IdempotencyKey:  req.IdempotencyKey,
+	})
+}
+
+// prepareUpsert menimpa undangan yang sudah ada dengan isi req. ID, waktu pembuatan dan
+// jumlah pengiriman ulang dipertahankan, sedangkan token dan masa berlaku diperbarui. Tanpa
+// ExpiresAt atau ExpiresIn, masa berlaku undangan lama dipakai kembali.
+func (s *invitationService) prepareUpsert(ctx context.Context, tx *redis.Tx, oldHash string, existing *InvitationData, req CreateInvitationRequest) (*pendingInvitation, error) {
+	now := s.now().UTC()
+	updated := *existing
+	updated.Role = req.Role
+	updated.InviterID = req.InviterID
+	updated.SourceIP = req.SourceIP
+	updated.UserAgent = req.UserAgent
+	updated.Message = req.Message
+	updated.Name = req.Name
+	updated.Locale = req.Locale
+	updated.ShortCode = req.ShortCode
+	updated.IdempotencyKey = req.IdempotencyKey
+	lifetime := s.lifetimeFor(req, now, existing.lifetime(s.ttl))
+	updated.ExpiresAt = now.Add(lifetime)
+	updated.LifetimeSeconds = int64(lifetime.Seconds())
+	updated.LastSentAt = now
+	updated.UpdatedAt = &now
+
+	p, err := s.preparePending(ctx, tx, updated)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	p.replacedHash = oldHash
+	p.previous = existing
+	return p, nil
+}
+
+// preparePending membuat token baru untuk data dan menyusun notifikasinya.
+func (s *invitationService) preparePending(ctx context.Context, tx *redis.Tx, data InvitationData) (*pendingInvitation, error) {
+	token, tokenHash, err := s.newToken(ctx, tx, &data)
+	if err != nil {
+		return nil, err
+	}
+	p := &pendingInvitation{tokenHash: tokenHash, data: data}
+	if p.payload, err = json.Marshal(p.data); err != nil {
+		return nil, err
+	}
+	if p.message, p.encodedMessage, err = s.newOutboxMessage(token, &p.data); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// write menambahkan perintah penyimpanan undangan ke transaksi pemanggil. Data undangan,
+// pointer ID, indeks email, entri indeks tenant dan notifikasi email ditulis dalam satu
+// transaksi agar daftar undangan tidak pernah menunjuk ke undangan yang tidak tersimpan dan
+// setiap undangan yang tersimpan pasti memiliki email yang menunggu dikirim. Token lama
+// undangan yang diganti langsung dihapus. Key undangan kedaluwarsa bersamaan dengan ExpiresAt.
+func (p *pendingInvitation) write(ctx context.Context, pipe redis.Pipeliner) {
+	ttl := p.data.ExpiresAt.Sub(p.data.LastSentAt)
+	if p.replacedHash != "" {
+		pipe.Del(ctx, tokenKey(p.replacedHash))
 	}
+	pipe.Set(ctx, tokenKey(p.tokenHash), p.payload, ttl)
+	pipe.Set(ctx, invitationIDKey(p.data.ID), p.tokenHash, ttl)
+	pipe.Set(ctx, emailIndexKey(p.data.TenantID, p.data.Email), p.data.ID, ttl)
+	pipe.ZAdd(ctx, tenantIndexKey(p.data.TenantID), redis.Z{Score: float64(p.data.CreatedAt.UnixMilli()), Member: p.data.ID})
+	writeOutbox(ctx, pipe, p.message, p.encodedMessage, p.data.LastSentAt.Add(outboxDispatchGrace))
+}
 
-	if err := s.redisClient.Set(ctx, redisKey, payload, s.ttl).Err(); err != nil {
-		return "", err
+// emitWritten menerbitkan invitation.created untuk undangan baru atau invitation.updated
+// untuk undangan yang diperbarui melalui upsert.
+func (s *invitationService) emitWritten(ctx context.Context, p *pendingInvitation) {
+	if p.previous == nil {
+		s.emitEvent(ctx, client.EventInvitationCreated, p.data.TenantID, &p.data)
+		return
 	}
+	s.emitEvent(ctx, client.EventInvitationUpdated, p.data.TenantID, invitationUpdatedPayload{InvitationData: &p.data, PreviousRole: p.previous.Role})
+}
 
-	invitationLink := fmt.Sprintf("https://app.prismerp.com/accept-invitation?token=%s", token)
+// invitationNotification menyusun email undangan berisi tautan penerimaan dan token mentah untuk
+// notification-service.
+func invitationNotification(invitationLink, token string, data *InvitationData) client.NotificationPayload {
 	notificationPayload := client.NotificationPayload{
-		Recipient:    email,
+		Recipient:    data.Email,
 		Subject:      "Anda Diundang untuk Bergabung dengan Prism ERP",
 		TemplateName: "invitation.html",
 		TemplateData: map[string]interface{}{
 			"InvitationLink": invitationLink,
-			"RecipientEmail": email,
+			"RecipientEmail": data.Email,
+			"ExpiresAt":      data.ExpiresAt.Format(time.RFC3339),
 		},
 	}
-
-	if err := s.queuePublisher.Enqueue(ctx, notificationPayload); err != nil {
-		log.Error().Err(err).Str("email", email).Msg("Gagal menerbitkan event undangan, undangan mungkin tidak terkirim.")
+	if data.Message != "" {
+		notificationPayload.TemplateData["InviterMessage"] = data.Message
 	}
-
-	return token, nil
+	if data.Name != "" {
+		notificationPayload.TemplateData["RecipientName"] = data.Name
+	}
+	if data.Locale != "" {
+		notificationPayload.TemplateData["Locale"] = data.Locale
+	}
+	if data.ShortCode {
+		notificationPayload.TemplateData["InvitationCode"] = token
+	}
+	return notificationPayload
 }
 
+// ValidateInvitation menerima undangan dan langsung mengonsumsi tokennya.
+// Undangan yang sedang direservasi hanya dapat dikonsumsi melalui CommitInvitation.
 func (s *invitationService) ValidateInvitation(ctx context.Context, token string) (*InvitationData, error) {
-	hash := sha256.Sum256([]byte(token))
-	tokenHash := base64.StdEncoding.EncodeToString(hash[:])
-	redisKey := fmt.Sprintf("invitation:%s", tokenHash)
+	tokenHash, err := s.lookupHash(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return s.consumeToken(ctx, tokenHash, "")
+}
+
+// PreviewInvitation mengembalikan data undangan tanpa mengonsumsi tokennya, sehingga
+// frontend dapat menampilkan detail undangan sebelum pengguna mendaftar.
+func (s *invitationService) PreviewInvitation(ctx context.Context, token string) (*InvitationData, error) {
+	tokenHash, err := s.lookupHash(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return s.readInvitation(ctx, tokenHash)
+}
 
-	payload, err := s.redisClient.Get(ctx, redisKey).Result()
+// readInvitation membaca data undangan berdasarkan hash token.
+func (s *invitationService) readInvitation(ctx context.Context, tokenHash string) (*InvitationData, error) {
+	payload, err := s.redisClient.Get(ctx, tokenKey(tokenHash)).Result()
 	if err == redis.Nil {
-		return nil, fmt.Errorf("undangan tidak valid atau sudah kedaluwarsa")
+		return nil, ErrInvalidToken
 	} else if err != nil {
 		return nil, err
 	}
@@ -90,10 +496,324 @@ func (s *invitationService) ValidateInvitation(ctx context.Context, token string
 	if err := json.Unmarshal([]byte(payload), &data); err != nil {
 		return nil, fmt.Errorf("gagal unmarshal data undangan: %w", err)
 	}
+	return &data, nil
+}
 
-	if err := s.redisClient.Del(ctx, redisKey).Err(); err != nil {
-		log.Warn().Err(err).Msg("PERINGATAN: gagal menghapus token undangan bekas pakai")
+// ListInvitations mengembalikan undangan yang masih berlaku untuk sebuah tenant,
+// diurutkan dari yang terbaru. Entri indeks yang datanya sudah kedaluwarsa
+// akan dibersihkan secara lazy selama pembacaan.
+func (s *invitationService) ListInvitations(ctx context.Context, tenantID string, filter ListFilter) (*InvitationPage, error) {
+	limit := filter.Limit
+	if limit < 1 {
+		limit = DefaultListLimit
+	}
+	if limit > MaxListLimit {
+		limit = MaxListLimit
 	}
 
-	return &data, nil
+	maxScore := "+inf"
+	var after *listCursor
+	if filter.Cursor != "" {
+		cur, err := decodeListCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = cur
+		maxScore = strconv.FormatInt(cur.Score, 10)
+	}
+
+	emailPrefix := strings.ToLower(filter.EmailPrefix)
+	page := &InvitationPage{Invitations: []InvitationData{}}
+	var lastIncluded redis.Z
+	var stale []string
+
+	for offset := int64(0); ; offset += listBatchSize {
+		entries, err := s.redisClient.ZRangeArgsWithScores(ctx, redis.ZRangeArgs{
+			Key:     tenantIndexKey(tenantID),
+			Start:   "-inf",
+			Stop:    maxScore,
+			ByScore: true,
+			Rev:     true,
+			Offset:  offset,
+			Count:   listBatchSize,
+		}).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		candidates := make([]redis.Z, 0, len(entries))
+		idKeys := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			member, _ := entry.Member.(string)
+			// Lewati entri yang sudah dikembalikan di halaman sebelumnya. Entri dengan skor
+			// yang sama diurutkan menurun secara leksikografis oleh ZRANGE ... REV.
+			if after != nil && int64(entry.Score) == after.Score && member >= after.Member {
+				continue
+			}
+			candidates = append(candidates, entry)
+			idKeys = append(idKeys, invitationIDKey(member))
+		}
+
+		if len(idKeys) > 0 {
+			values, err := s.resolveInvitations(ctx, idKeys)
+			if err != nil {
+				return nil, err
+			}
+			for i, value := range values {
+				raw, ok := value.(string)
+				if !ok {
+					stale = append(stale, candidates[i].Member.(string))
+					continue
+				}
+				var data InvitationData
+				if err := json.Unmarshal([]byte(raw), &data); err != nil {
+					log.Warn().Err(err).Str("tenant_id", tenantID).Msg("Melewati data undangan yang tidak dapat dibaca")
+					continue
+				}
+				if filter.Role != "" && data.Role != filter.Role {
+					continue
+				}
+				if emailPrefix != "" && !strings.HasPrefix(strings.ToLower(data.Email), emailPrefix) {
+					continue
+				}
+				// Satu entri tambahan dibaca hanya untuk mengetahui apakah halaman berikutnya ada.
+				if len(page.Invitations) == limit {
+					page.NextCursor = encodeListCursor(lastIncluded)
+					reapExpired(ctx, s.redisClient, s.eventPublisher, s.now(), tenantID, stale)
+					return page, nil
+				}
+				page.Invitations = append(page.Invitations, data)
+				lastIncluded = candidates[i]
+			}
+		}
+
+		if len(entries) < listBatchSize {
+			break
+		}
+	}
+
+	reapExpired(ctx, s.redisClient, s.eventPublisher, s.now(), tenantID, stale)
+	return page, nil
+}
+
+// resolveInvitations membaca data undangan untuk sekumpulan key pointer ID.
+// Posisi hasil sesuai dengan idKeys; nilai nil berarti undangan sudah tidak ada.
+func (s *invitationService) resolveInvitations(ctx context.Context, idKeys []string) ([]interface{}, error) {
+	hashes, err := s.redisClient.MGet(ctx, idKeys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(hashes))
+	dataKeys := make([]string, 0, len(hashes))
+	positions := make([]int, 0, len(hashes))
+	for i, hash := range hashes {
+		if tokenHash, ok := hash.(string); ok {
+			dataKeys = append(dataKeys, tokenKey(tokenHash))
+			positions = append(positions, i)
+		}
+	}
+	if len(dataKeys) == 0 {
+		return values, nil
+	}
+
+	records, err := s.redisClient.MGet(ctx, dataKeys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, record := range records {
+		values[positions[i]] = record
+	}
+	return values, nil
+}
+
+// RevokeInvitation membatalkan undangan yang masih berlaku. Hash token, pointer ID dan
+// entri indeks dihapus dalam satu transaksi, dan catatan pencabutan disimpan untuk audit.
+func (s *invitationService) RevokeInvitation(ctx context.Context, tenantID, invitationID, revokedBy string) (*RevocationRecord, error) {
+	var record *RevocationRecord
+	txf := func(tx *redis.Tx) error {
+		tokenHash, data, err := loadInvitationByID(ctx, tx, tenantID, invitationID)
+		if err != nil {
+			return err
+		}
+		// Pastikan token tidak dikonsumsi secara bersamaan sebelum transaksi dijalankan.
+		if err := tx.Watch(ctx, tokenKey(tokenHash)).Err(); err != nil {
+			return err
+		}
+
+		record = &RevocationRecord{
+			InvitationID: data.ID,
+			TenantID:     data.TenantID,
+			Email:        data.Email,
+			RevokedBy:    revokedBy,
+			RevokedAt:    s.now().UTC(),
+		}
+		payload, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, tokenKey(tokenHash), invitationIDKey(invitationID))
+			pipe.ZRem(ctx, tenantIndexKey(tenantID), invitationID)
+			pipe.Set(ctx, revocationKey(invitationID), payload, revocationRetention)
+			return nil
+		})
+		return err
+	}
+
+	if err := s.withWatch(ctx, txf, invitationIDKey(invitationID)); err != nil {
+		return nil, err
+	}
+	s.releaseEmailIndex(ctx, tenantID, record.Email, invitationID)
+
+	log.Info().
+		Str("invitation_id", invitationID).
+		Str("tenant_id", tenantID).
+		Str("revoked_by", revokedBy).
+		Msg("Undangan dicabut")
+	s.emitEvent(ctx, client.EventInvitationRevoked, tenantID, record)
+	return record, nil
+}
+
+// ResendInvitation mengirim ulang undangan dengan token baru. Token lama langsung tidak berlaku,
+// masa berlaku diperpanjang sepanjang masa berlaku yang dipilih saat undangan dibuat, dan batas
+// serta jeda pengiriman ulang ditegakkan.
+func (s *invitationService) ResendInvitation(ctx context.Context, tenantID, invitationID, resentBy string) (*InvitationData, error) {
+	var pending *pendingInvitation
+	txf := func(tx *redis.Tx) error {
+		oldHash, data, err := loadInvitationByID(ctx, tx, tenantID, invitationID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Watch(ctx, tokenKey(oldHash)).Err(); err != nil {
+			return err
+		}
+
+		now := s.now().UTC()
+		if data.ResendCount >= s.maxResends {
+			return ErrResendLimitReached
+		}
+		if wait := data.LastSentAt.Add(s.resendCooldown).Sub(now); wait > 0 {
+			return &RetryAfterError{Err: ErrResendCooldown, RetryAfter: wait}
+		}
+
+		updated := *data
+		updated.ResendCount++
+		updated.LastSentAt = now
+		updated.ExpiresAt = now.Add(data.lifetime(s.ttl))
+		if pending, err = s.preparePending(ctx, tx, updated); err != nil {
+			return err
+		}
+		pending.replacedHash = oldHash
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pending.write(ctx, pipe)
+			return nil
+		})
+		return err
+	}
+
+	if err := s.withWatch(ctx, txf, invitationIDKey(invitationID)); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("invitation_id", invitationID).
+		Str("tenant_id", tenantID).
+		Str("resent_by", resentBy).
+		Int("resend_count", pending.data.ResendCount).
+		Msg("Undangan dikirim ulang dengan token baru")
+
+	s.dispatchOutbox(ctx, pending.message)
+	s.emitEvent(ctx, client.EventInvitationResent, tenantID, invitationResentPayload{InvitationData: &pending.data, ResentBy: resentBy})
+	return &pending.data, nil
+}
+
+// withWatch menjalankan transaksi optimistik dan mengulanginya jika key yang diawasi berubah.
+func (s *invitationService) withWatch(ctx context.Context, txf func(tx *redis.Tx) error, keys ...string) error {
+	for i := 0; i < maxTxRetries; i++ {
+		err := s.redisClient.Watch(ctx, txf, keys...)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return fmt.Errorf("transaksi undangan gagal setelah %d percobaan: %w", maxTxRetries, redis.TxFailedErr)
+}
+
+// loadInvitationByID membaca hash token dan data undangan berdasarkan ID. Undangan milik
+// tenant lain diperlakukan sebagai tidak ditemukan agar keberadaannya tidak bocor.
+func loadInvitationByID(ctx context.Context, rdb redis.Cmdable, tenantID, invitationID string) (string, *InvitationData, error) {
+	tokenHash, err := rdb.Get(ctx, invitationIDKey(invitationID)).Result()
+	if err == redis.Nil {
+		return "", nil, ErrInvitationNotFound
+	} else if err != nil {
+		return "", nil, err
+	}
+
+	payload, err := rdb.Get(ctx, tokenKey(tokenHash)).Result()
+	if err == redis.Nil {
+		return "", nil, ErrInvitationNotFound
+	} else if err != nil {
+		return "", nil, err
+	}
+
+	var data InvitationData
+	if err := json.Unmarshal([]byte(payload), &data); err != nil {
+		return "", nil, fmt.Errorf("gagal unmarshal data undangan: %w", err)
+	}
+	if data.TenantID != tenantID {
+		return "", nil, ErrInvitationNotFound
+	}
+	return tokenHash, &data, nil
+}
+
+// removeFromIndex menghapus pointer ID, entri indeks tenant dan indeks email setelah undangan dikonsumsi.
+func (s *invitationService) removeFromIndex(ctx context.Context, data *InvitationData) {
+	_, err := s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, invitationIDKey(data.ID))
+		pipe.ZRem(ctx, tenantIndexKey(data.TenantID), data.ID)
+		return nil
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("invitation_id", data.ID).Msg("Gagal menghapus undangan dari indeks tenant")
+	}
+	s.releaseEmailIndex(ctx, data.TenantID, data.Email, data.ID)
+}
+
+// releaseEmailIndex menghapus indeks email hanya jika masih menunjuk ke invitationID, sehingga
+// undangan baru untuk email yang sama tidak ikut terlepas. Kegagalan hanya dicatat karena
+// indeks yang menunjuk ke undangan yang sudah tidak berlaku diabaikan saat pembuatan undangan.
+func (s *invitationService) releaseEmailIndex(ctx context.Context, tenantID, email, invitationID string) {
+	if err := releaseScript.Run(ctx, s.redisClient, []string{emailIndexKey(tenantID, email)}, invitationID).Err(); err != nil {
+		log.Warn().Err(err).Str("invitation_id", invitationID).Msg("Gagal menghapus indeks email undangan")
+	}
+}
+
+// listCursor menandai posisi terakhir yang dikembalikan ListInvitations.
+type listCursor struct {
+	Score  int64
+	Member string
+}
+
+func encodeListCursor(z redis.Z) string {
+	member, _ := z.Member.(string)
+	raw := fmt.Sprintf("%d:%s", int64(z.Score), member)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeListCursor(cursor string) (*listCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	scorePart, member, found := strings.Cut(string(raw), ":")
+	if !found || member == "" {
+		return nil, ErrInvalidCursor
+	}
+	score, err := strconv.ParseInt(scorePart, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &listCursor{Score: score, Member: member}, nil
 }
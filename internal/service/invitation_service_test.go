@@ -7,51 +7,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/client"
 	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
-// --- Mocks untuk Dependensi ---
-
-// Mock untuk QueuePublisher (menggantikan MockNotificationClient)
-type MockQueuePublisher struct {
-	mock.Mock
-}
-
-// Implementasi interface QueuePublisher
-func (m *MockQueuePublisher) Enqueue(ctx context.Context, payload client.NotificationPayload) error {
-	args := m.Called(ctx, payload)
-	return args.Error(0)
-}
-
-func (m *MockQueuePublisher) Close() error {
-	args := m.Called()
-	return args.Error(0)
-}
-
-// Pastikan mock memenuhi interface.
-var _ client.QueuePublisher = (*MockQueuePublisher)(nil)
-
-// MockTokenGenerator tetap sama.
-type MockTokenGenerator struct {
-	TokenToReturn string
-}
-
-func (m *MockTokenGenerator) Generate() string {
-	if m.TokenToReturn != "" {
-		return m.TokenToReturn
-	}
-	return "fixed-mock-token"
-}
-
-var _ TokenGenerator = (*MockTokenGenerator)(nil)
-
 func TestInvitationService_CreateInvitation(t *testing.T) {
 	ctx := context.Background()
 	email := "new.user@example.com"
@@ -61,6 +28,7 @@ func TestInvitationService_CreateInvitation(t *testing.T) {
 	ttlDuration := time.Hour * 24 * 7
 	ttlHours := 7 * 24
 	fixedToken := "this-is-a-fixed-token-for-testing"
+	emailKey := "invitation_email:" + tenantID + ":" + email
 
 	t.Run("Success", func(t *testing.T) {
 		// Arrange
@@ -68,24 +36,70 @@ func TestInvitationService_CreateInvitation(t *testing.T) {
 		mockPublisher := new(MockQueuePublisher) // Menggunakan mock publisher baru
 		mockTokenGen := &MockTokenGenerator{TokenToReturn: fixedToken}
 		// DIUBAH: Inject mock publisher ke service
-		svc := NewInvitationService(redisClient, mockPublisher, mockTokenGen, ttlHours)
+		svc := newTestService(redisClient, mockPublisher, mockTokenGen, ttlHours)
 
 		hash := sha256.Sum256([]byte(fixedToken))
 		tokenHash := base64.StdEncoding.EncodeToString(hash[:])
 		expectedRedisKey := fmt.Sprintf("invitation:%s", tokenHash)
-		expectedData := InvitationData{Email: email, Role: role, TenantID: tenantID}
+		req := CreateInvitationRequest{
+			Email:     email,
+			Role:      role,
+			TenantID:  tenantID,
+			InviterID: inviterID,
+			SourceIP:  "203.0.113.7",
+			UserAgent: "Mozilla/5.0",
+			Message:   "Selamat bergabung!",
+		}
+		expectedData := InvitationData{
+			ID:         fixedInvitationID,
+			Email:      email,
+			Role:       role,
+			TenantID:   tenantID,
+			InviterID:  inviterID,
+			CreatedAt:  fixedNow,
+			ExpiresAt:  fixedNow.Add(ttlDuration),
+			LastSentAt: fixedNow,
+			// Masa berlaku default disimpan agar pengiriman ulang memakai durasi yang sama.
+			LifetimeSeconds: int64(ttlDuration.Seconds()),
+			SourceIP:        "203.0.113.7",
+			UserAgent:       "Mozilla/5.0",
+			Message:         "Selamat bergabung!",
+		}
 		expectedPayload, _ := json.Marshal(expectedData)
+		expectedMessage, _ := json.Marshal(OutboxMessage{
+			ID:           fixedInvitationID,
+			InvitationID: fixedInvitationID,
+			Payload:      invitationNotification("https://app.prismerp.com/accept-invitation?token="+fixedToken, fixedToken, &expectedData),
+			CreatedAt:    fixedNow,
+		})
 
+		// Undangan, indeks email dan notifikasinya ditulis dalam satu transaksi.
+		mockRedis.ExpectWatch(emailKey)
+		mockRedis.ExpectGet(emailKey).RedisNil()
+		mockRedis.ExpectTxPipeline()
 		mockRedis.ExpectSet(expectedRedisKey, expectedPayload, ttlDuration).SetVal("OK")
+		mockRedis.ExpectSet("invitation_id:"+fixedInvitationID, tokenHash, ttlDuration).SetVal("OK")
+		mockRedis.ExpectSet(emailKey, fixedInvitationID, ttlDuration).SetVal("OK")
+		mockRedis.ExpectZAdd("invitation_index:"+tenantID, redis.Z{Score: float64(fixedNow.UnixMilli()), Member: fixedInvitationID}).SetVal(1)
+		mockRedis.ExpectHSet(outboxMessagesKey, fixedInvitationID, expectedMessage).SetVal(1)
+		mockRedis.ExpectZAdd(outboxPendingKey, redis.Z{Score: float64(fixedNow.Add(outboxDispatchGrace).UnixMilli()), Member: fixedInvitationID}).SetVal(1)
+		mockRedis.ExpectTxPipelineExec()
 		// DIUBAH: Ekspektasi sekarang adalah pemanggilan Enqueue dengan payload yang benar.
-		mockPublisher.On("Enqueue", ctx, mock.AnythingOfType("client.NotificationPayload")).Return(nil).Once()
+		mockPublisher.On("Enqueue", ctx, mock.MatchedBy(func(p client.NotificationPayload) bool {
+			return p.Recipient == email && p.TemplateData["InviterMessage"] == "Selamat bergabung!"
+		})).Return(nil).Once()
+		// Pesan yang sudah diterima broker dihapus dari outbox.
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZRem(outboxPendingKey, fixedInvitationID).SetVal(1)
+		mockRedis.ExpectHDel(outboxMessagesKey, fixedInvitationID).SetVal(1)
+		mockRedis.ExpectTxPipelineExec()
 
 		// Act
-		token, err := svc.CreateInvitation(ctx, email, role, tenantID, inviterID)
+		invitation, err := svc.CreateInvitation(ctx, req)
 
 		// Assert
 		require.NoError(t, err)
-		assert.Equal(t, fixedToken, token)
+		assert.Equal(t, expectedData, *invitation)
 		assert.NoError(t, mockRedis.ExpectationsWereMet())
 		mockPublisher.AssertExpectations(t)
 	})
@@ -95,31 +109,44 @@ func TestInvitationService_CreateInvitation(t *testing.T) {
 		redisClient, mockRedis := redismock.NewClientMock()
 		mockPublisher := new(MockQueuePublisher) // Gunakan mock publisher
 		mockTokenGen := &MockTokenGenerator{TokenToReturn: fixedToken}
-		svc := NewInvitationService(redisClient, mockPublisher, mockTokenGen, ttlHours)
+		svc := newTestService(redisClient, mockPublisher, mockTokenGen, ttlHours)
 		expectedError := errors.New("redis connection failed")
 
 		hash := sha256.Sum256([]byte(fixedToken))
 		tokenHash := base64.StdEncoding.EncodeToString(hash[:])
 		expectedRedisKey := fmt.Sprintf("invitation:%s", tokenHash)
-		expectedData := InvitationData{Email: email, Role: role, TenantID: tenantID}
+		expectedData := InvitationData{
+			ID:              fixedInvitationID,
+			Email:           email,
+			Role:            role,
+			TenantID:        tenantID,
+			InviterID:       inviterID,
+			CreatedAt:       fixedNow,
+			ExpiresAt:       fixedNow.Add(ttlDuration),
+			LastSentAt:      fixedNow,
+			LifetimeSeconds: int64(ttlDuration.Seconds()),
+		}
 		expectedPayload, _ := json.Marshal(expectedData)
 
+		mockRedis.ExpectWatch(emailKey)
+		mockRedis.ExpectGet(emailKey).RedisNil()
+		mockRedis.ExpectTxPipeline()
 		mockRedis.ExpectSet(expectedRedisKey, expectedPayload, ttlDuration).SetErr(expectedError)
 
 		// Act
-		token, err := svc.CreateInvitation(ctx, email, role, tenantID, inviterID)
+		invitation, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: email, Role: role, TenantID: tenantID, InviterID: inviterID})
 
 		// Assert
 		require.Error(t, err)
 		assert.Equal(t, expectedError, err)
-		assert.Empty(t, token)
+		assert.Nil(t, invitation)
 		assert.NoError(t, mockRedis.ExpectationsWereMet())
 		// Verifikasi bahwa Enqueue tidak pernah dipanggil jika penyimpanan Redis gagal.
 		mockPublisher.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
 	})
 }
 
-// TestValidateInvitation tidak perlu diubah karena tidak berinteraksi dengan publisher.
+// TestValidateInvitation memverifikasi bahwa token dikonsumsi melalui skrip Lua atomik.
 func TestInvitationService_ValidateInvitation(t *testing.T) {
 	ctx := context.Background()
 	token := "valid-token-string"
@@ -127,17 +154,22 @@ func TestInvitationService_ValidateInvitation(t *testing.T) {
 	hash := sha256.Sum256([]byte(token))
 	tokenHash := base64.StdEncoding.EncodeToString(hash[:])
 	expectedRedisKey := fmt.Sprintf("invitation:%s", tokenHash)
+	consumeKeys := []string{expectedRedisKey, "invitation_reservation:" + tokenHash}
 
 	t.Run("Success - Valid Token", func(t *testing.T) {
 		redisClient, mockRedis := redismock.NewClientMock()
 		// Kirim nil untuk publisher karena tidak digunakan di sini.
 		svc := NewInvitationService(redisClient, nil, &MockTokenGenerator{}, 1)
 
-		expectedData := InvitationData{Email: "valid.user@example.com", Role: "editor"}
+		expectedData := InvitationData{ID: "inv-1", Email: "valid.user@example.com", Role: "editor", TenantID: "tenant-123"}
 		payload, _ := json.Marshal(expectedData)
 
-		mockRedis.ExpectGet(expectedRedisKey).SetVal(string(payload))
-		mockRedis.ExpectDel(expectedRedisKey).SetVal(1)
+		mockRedis.ExpectEvalSha(consumeScript.Hash(), consumeKeys, "").SetVal([]interface{}{"ok", string(payload)})
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectDel("invitation_id:inv-1").SetVal(1)
+		mockRedis.ExpectZRem("invitation_index:tenant-123", "inv-1").SetVal(1)
+		mockRedis.ExpectTxPipelineExec()
+		mockRedis.ExpectEvalSha(releaseScript.Hash(), []string{"invitation_email:tenant-123:valid.user@example.com"}, "inv-1").SetVal(int64(1))
 
 		data, err := svc.ValidateInvitation(ctx, token)
 
@@ -151,7 +183,7 @@ func TestInvitationService_ValidateInvitation(t *testing.T) {
 	t.Run("Failure - Token Not Found", func(t *testing.T) {
 		redisClient, mockRedis := redismock.NewClientMock()
 		svc := NewInvitationService(redisClient, nil, &MockTokenGenerator{}, 1)
-		mockRedis.ExpectGet(expectedRedisKey).RedisNil()
+		mockRedis.ExpectEvalSha(consumeScript.Hash(), consumeKeys, "").SetVal([]interface{}{"invalid"})
 
 		data, err := svc.ValidateInvitation(ctx, "valid-token-string")
 
@@ -161,3 +193,423 @@ func TestInvitationService_ValidateInvitation(t *testing.T) {
 		assert.NoError(t, mockRedis.ExpectationsWereMet())
 	})
 }
+
+func TestInvitationService_ListInvitations(t *testing.T) {
+	ctx := context.Background()
+	tenantID := "tenant-123"
+	indexKey := "invitation_index:" + tenantID
+
+	admin := InvitationData{Email: "Alice@example.com", Role: "admin", TenantID: tenantID, InviterID: "inviter-1"}
+	viewer := InvitationData{Email: "bob@example.com", Role: "viewer", TenantID: tenantID, InviterID: "inviter-1"}
+	adminPayload, _ := json.Marshal(admin)
+	viewerPayload, _ := json.Marshal(viewer)
+
+	entries := []redis.Z{
+		{Score: 3000, Member: "inv-admin"},
+		{Score: 2000, Member: "inv-expired"},
+		{Score: 1000, Member: "inv-viewer"},
+	}
+	rangeArgs := func(max string) redis.ZRangeArgs {
+		return redis.ZRangeArgs{Key: indexKey, Start: "-inf", Stop: max, ByScore: true, Rev: true, Count: listBatchSize}
+	}
+
+	t.Run("Success - Filter By Email Prefix And Prune Expired", func(t *testing.T) {
+		redisClient, mockRedis := redismock.NewClientMock()
+		svc := NewInvitationService(redisClient, nil, &MockTokenGenerator{}, 1)
+
+		mockRedis.ExpectZRangeArgsWithScores(rangeArgs("+inf")).SetVal(entries)
+		mockRedis.ExpectMGet("invitation_id:inv-admin", "invitation_id:inv-expired", "invitation_id:inv-viewer").
+			SetVal([]interface{}{"hash-admin", nil, "hash-viewer"})
+		mockRedis.ExpectMGet("invitation:hash-admin", "invitation:hash-viewer").
+			SetVal([]interface{}{string(adminPayload), string(viewerPayload)})
+		mockRedis.ExpectZRem(indexKey, "inv-expired").SetVal(1)
+
+		page, err := svc.ListInvitations(ctx, tenantID, ListFilter{EmailPrefix: "alice"})
+
+		require.NoError(t, err)
+		require.Len(t, page.Invitations, 1)
+		assert.Equal(t, "Alice@example.com", page.Invitations[0].Email)
+		assert.Empty(t, page.NextCursor)
+		assert.NoError(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("Success - Cursor Pagination", func(t *testing.T) {
+		redisClient, mockRedis := redismock.NewClientMock()
+		svc := NewInvitationService(redisClient, nil, &MockTokenGenerator{}, 1)
+
+		mockRedis.ExpectZRangeArgsWithScores(rangeArgs("+inf")).SetVal([]redis.Z{entries[0], entries[2]})
+		mockRedis.ExpectMGet("invitation_id:inv-admin", "invitation_id:inv-viewer").
+			SetVal([]interface{}{"hash-admin", "hash-viewer"})
+		mockRedis.ExpectMGet("invitation:hash-admin", "invitation:hash-viewer").
+			SetVal([]interface{}{string(adminPayload), string(viewerPayload)})
+
+		first, err := svc.ListInvitations(ctx, tenantID, ListFilter{Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, first.Invitations, 1)
+		assert.Equal(t, "admin", first.Invitations[0].Role)
+		require.NotEmpty(t, first.NextCursor)
+
+		// Halaman kedua dimulai dari skor cursor (inklusif) dan melewati entri yang sudah dikembalikan.
+		mockRedis.ExpectZRangeArgsWithScores(rangeArgs("3000")).SetVal([]redis.Z{entries[0], entries[2]})
+		mockRedis.ExpectMGet("invitation_id:inv-viewer").SetVal([]interface{}{"hash-viewer"})
+		mockRedis.ExpectMGet("invitation:hash-viewer").SetVal([]interface{}{string(viewerPayload)})
+
+		second, err := svc.ListInvitations(ctx, tenantID, ListFilter{Limit: 1, Cursor: first.NextCursor})
+		require.NoError(t, err)
+		require.Len(t, second.Invitations, 1)
+		assert.Equal(t, "viewer", second.Invitations[0].Role)
+		assert.Empty(t, second.NextCursor)
+		assert.NoError(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("Failure - Invalid Cursor", func(t *testing.T) {
+		redisClient, _ := redismock.NewClientMock()
+		svc := NewInvitationService(redisClient, nil, &MockTokenGenerator{}, 1)
+
+		page, err := svc.ListInvitations(ctx, tenantID, ListFilter{Cursor: "%%%"})
+
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+		assert.Nil(t, page)
+	})
+}
+
+func TestInvitationService_RevokeInvitation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		svc, mr := newDeliveringService(t, &MockTokenGenerator{TokenToReturn: "revoke-me"})
+
+		invitation, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "user@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+
+		record, err := svc.RevokeInvitation(ctx, "tenant-1", invitation.ID, "admin-1")
+
+		require.NoError(t, err)
+		assert.Equal(t, invitation.ID, record.InvitationID)
+		assert.Equal(t, "admin-1", record.RevokedBy)
+		assert.Equal(t, fixedNow, record.RevokedAt)
+		assert.False(t, mr.Exists("invitation:"+hashToken("revoke-me")))
+		assert.False(t, mr.Exists("invitation_id:"+invitation.ID))
+		assert.True(t, mr.Exists("invitation_revocation:"+invitation.ID))
+		members, _ := mr.ZMembers("invitation_index:tenant-1")
+		assert.NotContains(t, members, invitation.ID)
+
+		_, err = svc.ValidateInvitation(ctx, "revoke-me")
+		assert.Error(t, err)
+	})
+
+	t.Run("Failure - Other Tenant", func(t *testing.T) {
+		svc, mr := newDeliveringService(t, &MockTokenGenerator{TokenToReturn: "not-yours"})
+
+		invitation, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "user@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+
+		record, err := svc.RevokeInvitation(ctx, "tenant-2", invitation.ID, "admin-2")
+
+		assert.ErrorIs(t, err, ErrInvitationNotFound)
+		assert.Nil(t, record)
+		assert.True(t, mr.Exists("invitation:"+hashToken("not-yours")))
+	})
+
+	t.Run("Failure - Unknown ID", func(t *testing.T) {
+		svc, _ := newMiniredisService(t, nil, &MockTokenGenerator{})
+
+		_, err := svc.RevokeInvitation(ctx, "tenant-1", "does-not-exist", "admin-1")
+
+		assert.ErrorIs(t, err, ErrInvitationNotFound)
+	})
+}
+
+func TestInvitationService_ResendInvitation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success - Rotates Token", func(t *testing.T) {
+		mockPublisher := new(MockQueuePublisher)
+		mockPublisher.On("Enqueue", ctx, mock.Anything).Return(nil)
+		tokenGen := &MockTokenGenerator{TokenToReturn: "first-token"}
+		svc, mr := newMiniredisService(t, mockPublisher, tokenGen)
+		svc.resendCooldown = time.Minute
+
+		invitation, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "user@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+
+		later := fixedNow.Add(2 * time.Minute)
+		svc.now = func() time.Time { return later }
+		tokenGen.TokenToReturn = "second-token"
+
+		resent, err := svc.ResendInvitation(ctx, "tenant-1", invitation.ID, "admin-1")
+
+		require.NoError(t, err)
+		assert.Equal(t, invitation.ID, resent.ID)
+		assert.Equal(t, 1, resent.ResendCount)
+		assert.Equal(t, later.Add(svc.ttl), resent.ExpiresAt)
+		assert.False(t, mr.Exists("invitation:"+hashToken("first-token")))
+		assert.True(t, mr.Exists("invitation:"+hashToken("second-token")))
+		mockPublisher.AssertNumberOfCalls(t, "Enqueue", 2)
+
+		data, err := svc.ValidateInvitation(ctx, "second-token")
+		require.NoError(t, err)
+		assert.Equal(t, invitation.ID, data.ID)
+	})
+
+	t.Run("Failure - Cooldown", func(t *testing.T) {
+		svc, _ := newDeliveringService(t, &MockTokenGenerator{TokenToReturn: "cooldown-token"})
+		svc.resendCooldown = 10 * time.Minute
+
+		invitation, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "user@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+
+		_, err = svc.ResendInvitation(ctx, "tenant-1", invitation.ID, "admin-1")
+
+		require.ErrorIs(t, err, ErrResendCooldown)
+		var retryErr *RetryAfterError
+		require.ErrorAs(t, err, &retryErr)
+		assert.Equal(t, 10*time.Minute, retryErr.RetryAfter)
+	})
+
+	t.Run("Failure - Limit Reached", func(t *testing.T) {
+		svc, _ := newDeliveringService(t, &MockTokenGenerator{TokenToReturn: "limited-token"})
+		svc.maxResends = 0
+
+		invitation, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "user@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+
+		_, err = svc.ResendInvitation(ctx, "tenant-1", invitation.ID, "admin-1")
+
+		assert.ErrorIs(t, err, ErrResendLimitReached)
+	})
+}
+
+func TestInvitationService_DuplicateGuard(t *testing.T) {
+	ctx := context.Background()
+	req := CreateInvitationRequest{Email: "user@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"}
+
+	t.Run("Conflict Returns Existing ID", func(t *testing.T) {
+		svc, _ := newDeliveringService(t, &UUIDTokenGenerator{})
+
+		first, err := svc.CreateInvitation(ctx, req)
+		require.NoError(t, err)
+
+		again := req
+		again.Email = " User@Example.COM"
+		_, err = svc.CreateInvitation(ctx, again)
+
+		require.ErrorIs(t, err, ErrDuplicateInvitation)
+		var dup *DuplicateInvitationError
+		require.ErrorAs(t, err, &dup)
+		assert.Equal(t, first.ID, dup.InvitationID)
+
+		otherTenant := req
+		otherTenant.TenantID = "tenant-2"
+		_, err = svc.CreateInvitation(ctx, otherTenant)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Upsert Rotates Token And Role", func(t *testing.T) {
+		events := &MockEventPublisher{}
+		tokenGen := &MockTokenGenerator{TokenToReturn: "first-token"}
+		svc, mr := newDeliveringService(t, tokenGen)
+		svc.eventPublisher = events
+
+		first, err := svc.CreateInvitation(ctx, req)
+		require.NoError(t, err)
+
+		later := fixedNow.Add(time.Hour)
+		svc.now = func() time.Time { return later }
+		tokenGen.TokenToReturn = "second-token"
+		upsert := req
+		upsert.Role = "admin"
+		upsert.Upsert = true
+		updated, err := svc.CreateInvitation(ctx, upsert)
+
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, updated.ID)
+		assert.Equal(t, "admin", updated.Role)
+		assert.Equal(t, fixedNow, updated.CreatedAt)
+		assert.Equal(t, later.Add(svc.ttl), updated.ExpiresAt)
+		require.NotNil(t, updated.UpdatedAt)
+		assert.False(t, mr.Exists("invitation:"+hashToken("first-token")))
+		members, _ := mr.ZMembers(tenantIndexKey("tenant-1"))
+		assert.Equal(t, []string{first.ID}, members)
+		assert.Equal(t, []string{client.EventInvitationCreated, client.EventInvitationUpdated}, events.Types())
+
+		data, err := svc.ValidateInvitation(ctx, "second-token")
+		require.NoError(t, err)
+		assert.Equal(t, "admin", data.Role)
+	})
+
+	t.Run("Released After Accept And Revoke", func(t *testing.T) {
+		tokenGen := &MockTokenGenerator{TokenToReturn: "accepted-token"}
+		svc, mr := newDeliveringService(t, tokenGen)
+
+		_, err := svc.CreateInvitation(ctx, req)
+		require.NoError(t, err)
+		_, err = svc.ValidateInvitation(ctx, "accepted-token")
+		require.NoError(t, err)
+		assert.False(t, mr.Exists(emailIndexKey("tenant-1", req.Email)))
+
+		tokenGen.TokenToReturn = "revoked-token"
+		second, err := svc.CreateInvitation(ctx, req)
+		require.NoError(t, err)
+		_, err = svc.RevokeInvitation(ctx, "tenant-1", second.ID, "admin-1")
+		require.NoError(t, err)
+
+		tokenGen.TokenToReturn = "third-token"
+		_, err = svc.CreateInvitation(ctx, req)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Stale Index Is Ignored", func(t *testing.T) {
+		svc, mr := newDeliveringService(t, &UUIDTokenGenerator{})
+		require.NoError(t, mr.Set(emailIndexKey("tenant-1", req.Email), "inv-gone"))
+
+		created, err := svc.CreateInvitation(ctx, req)
+
+		require.NoError(t, err)
+		owner, _ := mr.Get(emailIndexKey("tenant-1", req.Email))
+		assert.Equal(t, created.ID, owner)
+	})
+
+	t.Run("Bulk Marks Duplicates", func(t *testing.T) {
+		svc, _ := newDeliveringService(t, &UUIDTokenGenerator{})
+		existing, err := svc.CreateInvitation(ctx, req)
+		require.NoError(t, err)
+
+		fresh := req
+		fresh.Email = "fresh@example.com"
+		results := svc.CreateInvitations(ctx, []CreateInvitationRequest{req, fresh, fresh})
+
+		var dup *DuplicateInvitationError
+		require.ErrorAs(t, results[0].Err, &dup)
+		assert.Equal(t, existing.ID, dup.InvitationID)
+		require.NoError(t, results[1].Err)
+		require.ErrorAs(t, results[2].Err, &dup)
+		assert.Equal(t, results[1].Invitation.ID, dup.InvitationID)
+	})
+}
+
+func TestInvitationService_PreviewAndReservation(t *testing.T) {
+	ctx := context.Background()
+	t.Run("Preview Does Not Consume Token", func(t *testing.T) {
+		svc, _ := newDeliveringService(t, &MockTokenGenerator{TokenToReturn: "preview-token"})
+		_, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "user@example.com", Role: "admin", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+
+		first, err := svc.PreviewInvitation(ctx, "preview-token")
+		require.NoError(t, err)
+		second, err := svc.PreviewInvitation(ctx, "preview-token")
+		require.NoError(t, err)
+
+		assert.Equal(t, first, second)
+		assert.Equal(t, "admin", first.Role)
+	})
+
+	t.Run("Reserve, Release, Reserve Again, Commit", func(t *testing.T) {
+		svc, mr := newDeliveringService(t, &MockTokenGenerator{TokenToReturn: "two-phase-token"})
+		ids := []string{"inv-1", "msg-1", "res-1", "res-rejected", "res-2"}
+		svc.newID = func() string {
+			id := ids[0]
+			ids = ids[1:]
+			return id
+		}
+		_, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "user@example.com", Role: "admin", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+
+		reservation, err := svc.ReserveInvitation(ctx, "two-phase-token")
+		require.NoError(t, err)
+		assert.Equal(t, "res-1", reservation.ID)
+		assert.Equal(t, fixedNow.Add(DefaultReservationLease), reservation.ExpiresAt)
+
+		// Selama reservasi berlaku, token tidak dapat diterima langsung atau direservasi ulang.
+		_, err = svc.ValidateInvitation(ctx, "two-phase-token")
+		assert.ErrorIs(t, err, ErrInvitationReserved)
+		_, err = svc.ReserveInvitation(ctx, "two-phase-token")
+		assert.ErrorIs(t, err, ErrInvitationReserved)
+
+		require.NoError(t, svc.ReleaseInvitation(ctx, "two-phase-token", "res-1"))
+
+		retry, err := svc.ReserveInvitation(ctx, "two-phase-token")
+		require.NoError(t, err)
+		_, err = svc.CommitInvitation(ctx, "two-phase-token", "res-1")
+		assert.ErrorIs(t, err, ErrReservationMismatch)
+
+		data, err := svc.CommitInvitation(ctx, "two-phase-token", retry.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "inv-1", data.ID)
+		assert.False(t, mr.Exists("invitation:"+hashToken("two-phase-token")))
+		assert.False(t, mr.Exists("invitation_reservation:"+hashToken("two-phase-token")))
+	})
+
+	t.Run("Reservation Lease Expires", func(t *testing.T) {
+		svc, mr := newDeliveringService(t, &MockTokenGenerator{TokenToReturn: "lease-token"})
+		_, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "user@example.com", Role: "admin", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+
+		_, err = svc.ReserveInvitation(ctx, "lease-token")
+		require.NoError(t, err)
+		mr.FastForward(DefaultReservationLease + time.Second)
+
+		_, err = svc.ValidateInvitation(ctx, "lease-token")
+		assert.NoError(t, err)
+	})
+}
+
+// TestInvitationService_ConcurrentConsumption memastikan token yang sama yang diterima dari
+// banyak goroutine sekaligus hanya pernah mengembalikan data undangan ke satu pemanggil.
+func TestInvitationService_ConcurrentConsumption(t *testing.T) {
+	ctx := context.Background()
+	const workers = 50
+
+	run := func(t *testing.T, svc *invitationService, consume func() (*InvitationData, error)) {
+		t.Helper()
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			successes int
+			start     = make(chan struct{})
+		)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				data, err := consume()
+				if err != nil {
+					assert.True(t, errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrReservationMismatch), "error tak terduga: %v", err)
+					return
+				}
+				assert.Equal(t, "race@example.com", data.Email)
+				mu.Lock()
+				successes++
+				mu.Unlock()
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		assert.Equal(t, 1, successes)
+	}
+
+	t.Run("Direct Accept", func(t *testing.T) {
+		svc, mr := newDeliveringService(t, &MockTokenGenerator{TokenToReturn: "race-token"})
+		_, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "race@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+
+		run(t, svc, func() (*InvitationData, error) {
+			return svc.ValidateInvitation(ctx, "race-token")
+		})
+		assert.False(t, mr.Exists("invitation:"+hashToken("race-token")))
+	})
+
+	t.Run("Commit Reservation", func(t *testing.T) {
+		svc, _ := newDeliveringService(t, &MockTokenGenerator{TokenToReturn: "race-commit-token"})
+		_, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "race@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+		reservation, err := svc.ReserveInvitation(ctx, "race-commit-token")
+		require.NoError(t, err)
+
+		run(t, svc, func() (*InvitationData, error) {
+			return svc.CommitInvitation(ctx, "race-commit-token", reservation.ID)
+		})
+	})
+}
@@ -0,0 +1,539 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// Status job undangan massal.
+const (
+	JobStatusPending   = "pending"
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+)
+
+// Status per baris pada pembuatan undangan massal, baik sinkron maupun melalui job.
+const (
+	RowStatusCreated   = "created"
+	RowStatusDuplicate = "duplicate"
+	RowStatusInvalid   = "invalid"
+	RowStatusFailed    = "failed"
+)
+
+const (
+	// jobRetention adalah lama state job disimpan sejak dibuat.
+	jobRetention = 7 * 24 * time.Hour
+	// jobQueueKey berisi ID job yang menunggu diproses; jobProcessingKey berisi job yang
+	// sedang diproses oleh salah satu worker.
+	jobQueueKey      = "invitation_job_queue"
+	jobProcessingKey = "invitation_job_processing"
+	// jobDelayedKey adalah sorted set job yang ditunda karena batas laju, dengan skor berupa
+	// waktu job boleh dilanjutkan (Unix milidetik).
+	jobDelayedKey = "invitation_job_delayed"
+	// jobDelayPollInterval adalah interval pemeriksaan job tertunda yang sudah jatuh tempo, dan
+	// minJobRetryDelay adalah jeda minimum sebelum baris yang terkena batas laju dicoba lagi.
+	jobDelayPollInterval = 5 * time.Second
+	minJobRetryDelay     = time.Second
+	// jobStaleAfter adalah batas waktu tanpa kemajuan sebelum job dianggap ditinggalkan
+	// oleh worker yang mati dan dikembalikan ke antrean.
+	jobStaleAfter = 5 * time.Minute
+	// maxJobErrors membatasi jumlah error baris yang disimpan per job.
+	maxJobErrors = 1000
+
+	// DefaultJobWorkers adalah jumlah worker job jika tidak dikonfigurasi.
+	DefaultJobWorkers = 4
+)
+
+// Job adalah state pemrosesan undangan massal yang dapat dibaca oleh replika mana pun.
+type Job struct {
+	ID          string     `json:"id"`
+	TenantID    string     `json:"tenant_id"`
+	CreatedBy   string     `json:"created_by"`
+	Source      string     `json:"source"`
+	Status      string     `json:"status"`
+	Total       int        `json:"total"`
+	Processed   int        `json:"processed"`
+	Created     int        `json:"created"`
+	Duplicate   int        `json:"duplicate"`
+	Invalid     int        `json:"invalid"`
+	Failed      int        `json:"failed"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// ResumeAt terisi selama job menunggu batas laju pembuatan undangan kembali tersedia.
+	ResumeAt *time.Time    `json:"resume_at,omitempty"`
+	Errors   []JobRowError `json:"errors"`
+}
+
+// JobRow adalah satu baris valid yang menunggu dibuat oleh worker. Row adalah nomor baris
+// pada masukan asal (indeks JSON atau nomor baris CSV).
+type JobRow struct {
+	Row     int                     `json:"row"`
+	Request CreateInvitationRequest `json:"request"`
+}
+
+// JobRowError mencatat baris yang tidak menghasilkan undangan beserta alasannya.
+type JobRowError struct {
+	Row    int    `json:"row"`
+	Email  string `json:"email"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	// InvitationID adalah undangan aktif yang sudah ada untuk baris berstatus duplicate.
+	InvitationID string `json:"invitation_id,omitempty"`
+}
+
+// JobService menyimpan job undangan massal di Redis. Job dibuat dalam status pending,
+// diisi baris secara bertahap dengan AppendRows, lalu diantrekan dengan StartJob.
+type JobService interface {
+	CreateJob(ctx context.Context, tenantID, createdBy, source string) (*Job, error)
+	AppendRows(ctx context.Context, jobID string, rows []JobRow, rejected []JobRowError) error
+	StartJob(ctx context.Context, jobID string) error
+	GetJob(ctx context.Context, tenantID, jobID string) (*Job, error)
+}
+
+type jobService struct {
+	redisClient *redis.Client
+	now         func() time.Time
+	newID       func() string
+}
+
+// NewJobService membuat penyimpanan job undangan massal.
+func NewJobService(redisClient *redis.Client) JobService {
+	return &jobService{redisClient: redisClient, now: time.Now, newID: uuid.NewString}
+}
+
+func jobKey(jobID string) string {
+	return fmt.Sprintf("invitation_job:%s", jobID)
+}
+
+// jobRowsKey adalah list baris yang belum diproses; worker menghapusnya dari depan.
+func jobRowsKey(jobID string) string {
+	return fmt.Sprintf("invitation_job_rows:%s", jobID)
+}
+
+func jobErrorsKey(jobID string) string {
+	return fmt.Sprintf("invitation_job_errors:%s", jobID)
+}
+
+func (s *jobService) CreateJob(ctx context.Context, tenantID, createdBy, source string) (*Job, error) {
+	now := s.now().UTC()
+	job := &Job{
+		ID:        s.newID(),
+		TenantID:  tenantID,
+		CreatedBy: createdBy,
+		Source:    source,
+		Status:    JobStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Errors:    []JobRowError{},
+	}
+	_, err := s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, jobKey(job.ID),
+			"tenant_id", job.TenantID,
+			"created_by", job.CreatedBy,
+			"source", job.Source,
+			"status", job.Status,
+			"created_at", now.UnixMilli(),
+			"updated_at", now.UnixMilli(),
+		)
+		pipe.Expire(ctx, jobKey(job.ID), jobRetention)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *jobService) AppendRows(ctx context.Context, jobID string, rows []JobRow, rejected []JobRowError) error {
+	encodedRows := make([]interface{}, len(rows))
+	for i, row := range rows {
+		encoded, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		encodedRows[i] = encoded
+	}
+	encodedErrors, err := encodeJobErrors(rejected)
+	if err != nil {
+		return err
+	}
+
+	key := jobKey(jobID)
+	_, err = s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, key, "total", int64(len(rows)+len(rejected)))
+		if len(rows) > 0 {
+			pipe.RPush(ctx, jobRowsKey(jobID), encodedRows...)
+			pipe.Expire(ctx, jobRowsKey(jobID), jobRetention)
+		}
+		recordJobErrors(ctx, pipe, jobID, rejected, encodedErrors)
+		pipe.HSet(ctx, key, "updated_at", s.now().UnixMilli())
+		return nil
+	})
+	return err
+}
+
+// StartJob mengantrekan job agar diproses oleh worker.
+func (s *jobService) StartJob(ctx context.Context, jobID string) error {
+	_, err := s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, jobKey(jobID), "status", JobStatusQueued, "updated_at", s.now().UnixMilli())
+		pipe.LPush(ctx, jobQueueKey, jobID)
+		return nil
+	})
+	return err
+}
+
+// GetJob membaca state job. Job milik tenant lain diperlakukan sebagai tidak ditemukan.
+func (s *jobService) GetJob(ctx context.Context, tenantID, jobID string) (*Job, error) {
+	pipe := s.redisClient.Pipeline()
+	fieldsCmd := pipe.HGetAll(ctx, jobKey(jobID))
+	errorsCmd := pipe.LRange(ctx, jobErrorsKey(jobID), 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	fields, rawErrors := fieldsCmd.Val(), errorsCmd.Val()
+	if len(fields) == 0 || fields["tenant_id"] != tenantID {
+		return nil, ErrJobNotFound
+	}
+
+	job := decodeJob(jobID, fields)
+	job.Errors = make([]JobRowError, 0, len(rawErrors))
+	for _, raw := range rawErrors {
+		var rowErr JobRowError
+		if err := json.Unmarshal([]byte(raw), &rowErr); err == nil {
+			job.Errors = append(job.Errors, rowErr)
+		}
+	}
+	return job, nil
+}
+
+func decodeJob(jobID string, fields map[string]string) *Job {
+	count := func(name string) int {
+		n, _ := strconv.Atoi(fields[name])
+		return n
+	}
+	timestamp := func(name string) time.Time {
+		ms, _ := strconv.ParseInt(fields[name], 10, 64)
+		return time.UnixMilli(ms).UTC()
+	}
+	job := &Job{
+		ID:        jobID,
+		TenantID:  fields["tenant_id"],
+		CreatedBy: fields["created_by"],
+		Source:    fields["source"],
+		Status:    fields["status"],
+		Total:     count("total"),
+		Processed: count("processed"),
+		Created:   count("created"),
+		Duplicate: count("duplicate"),
+		Invalid:   count("invalid"),
+		Failed:    count("failed"),
+		CreatedAt: timestamp("created_at"),
+		UpdatedAt: timestamp("updated_at"),
+	}
+	if _, ok := fields["completed_at"]; ok {
+		completedAt := timestamp("completed_at")
+		job.CompletedAt = &completedAt
+	}
+	if _, ok := fields["resume_at"]; ok {
+		resumeAt := timestamp("resume_at")
+		job.ResumeAt = &resumeAt
+	}
+	return job
+}
+
+func encodeJobErrors(rowErrors []JobRowError) ([]interface{}, error) {
+	encoded := make([]interface{}, len(rowErrors))
+	for i, rowErr := range rowErrors {
+		raw, err := json.Marshal(rowErr)
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = raw
+	}
+	return encoded, nil
+}
+
+// recordJobErrors menambah penghitung status dan daftar error untuk baris yang sudah
+// selesai diproses tanpa menghasilkan undangan.
+func recordJobErrors(ctx context.Context, pipe redis.Pipeliner, jobID string, rowErrors []JobRowError, encoded []interface{}) {
+	if len(rowErrors) == 0 {
+		return
+	}
+	key := jobKey(jobID)
+	pipe.HIncrBy(ctx, key, "processed", int64(len(rowErrors)))
+	for _, rowErr := range rowErrors {
+		pipe.HIncrBy(ctx, key, rowErr.Status, 1)
+	}
+	pipe.RPush(ctx, jobErrorsKey(jobID), encoded...)
+	pipe.LTrim(ctx, jobErrorsKey(jobID), 0, maxJobErrors-1)
+	pipe.Expire(ctx, jobErrorsKey(jobID), jobRetention)
+}
+
+// requeueScript mengembalikan job dari daftar diproses ke antrean hanya jika job tersebut
+// belum diambil kembali oleh pemanggil lain.
+//
+// KEYS[1] = daftar job diproses, KEYS[2] = antrean job. ARGV[1] = ID job.
+var requeueScript = redis.NewScript(`
+if redis.call('LREM', KEYS[1], 1, ARGV[1]) == 1 then
+	redis.call('RPUSH', KEYS[2], ARGV[1])
+	return 1
+end
+return 0
+`)
+
+// promoteDelayedScript memindahkan job tertunda yang sudah jatuh tempo kembali ke antrean.
+//
+// KEYS[1] = sorted set job tertunda, KEYS[2] = antrean job. ARGV[1] = waktu sekarang (Unix milidetik).
+var promoteDelayedScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+for _, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[1], id)
+	redis.call('LPUSH', KEYS[2], id)
+end
+return #ids
+`)
+
+// JobRunner menjalankan sekumpulan worker yang memproses job undangan massal dari antrean
+// Redis. Setiap replika dapat menjalankan JobRunner; job diambil secara atomik sehingga
+// satu job hanya diproses oleh satu worker pada satu waktu.
+type JobRunner struct {
+	redisClient *redis.Client
+	invitations InvitationService
+	workers     int
+	pollTimeout time.Duration
+	now         func() time.Time
+}
+
+// NewJobRunner membuat JobRunner dengan jumlah worker tertentu.
+func NewJobRunner(redisClient *redis.Client, invitations InvitationService, workers int) *JobRunner {
+	if workers <= 0 {
+		workers = DefaultJobWorkers
+	}
+	return &JobRunner{redisClient: redisClient, invitations: invitations, workers: workers, pollTimeout: time.Second, now: time.Now}
+}
+
+// Run menjalankan worker dan pemulih job yang ditinggalkan hingga ctx dibatalkan.
+func (r *JobRunner) Run(ctx context.Context) {
+	for i := 0; i < r.workers; i++ {
+		go r.work(ctx)
+	}
+
+	ticker := time.NewTicker(jobStaleAfter / 5)
+	defer ticker.Stop()
+	delayTicker := time.NewTicker(jobDelayPollInterval)
+	defer delayTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-delayTicker.C:
+			if _, err := r.PromoteDelayed(ctx); err != nil {
+				log.Error().Err(err).Msg("Gagal melanjutkan job undangan yang tertunda")
+			}
+		case <-ticker.C:
+			if n, err := r.RequeueStale(ctx); err != nil {
+				log.Error().Err(err).Msg("Gagal memulihkan job undangan yang ditinggalkan")
+			} else if n > 0 {
+				log.Warn().Int("count", n).Msg("Job undangan yang ditinggalkan dikembalikan ke antrean")
+			}
+		}
+	}
+}
+
+func (r *JobRunner) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		jobID, err := r.redisClient.BLMove(ctx, jobQueueKey, jobProcessingKey, "RIGHT", "LEFT", r.pollTimeout).Result()
+		if err == redis.Nil || ctx.Err() != nil {
+			continue
+		} else if err != nil {
+			log.Error().Err(err).Msg("Gagal mengambil job undangan dari antrean")
+			time.Sleep(r.pollTimeout)
+			continue
+		}
+		if err := r.Process(ctx, jobID); err != nil {
+			// Job tetap berada di daftar diproses dan akan dikembalikan ke antrean oleh RequeueStale.
+			log.Error().Err(err).Str("job_id", jobID).Msg("Gagal memproses job undangan")
+		}
+	}
+}
+
+// Process memproses seluruh baris job yang tersisa per kelompok, lalu menandai job selesai.
+// Kemajuan dicatat setiap kelompok sehingga job yang terputus dapat dilanjutkan oleh worker lain.
+// Jika seluruh baris sebuah kelompok terkena batas laju, job ditunda hingga batasnya tersedia.
+func (r *JobRunner) Process(ctx context.Context, jobID string) error {
+	key := jobKey(jobID)
+	_, err := r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "status", JobStatusRunning, "updated_at", r.now().UnixMilli())
+		pipe.HDel(ctx, key, "resume_at")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		rawRows, err := r.redisClient.LRange(ctx, jobRowsKey(jobID), 0, bulkChunkSize-1).Result()
+		if err != nil {
+			return err
+		}
+		if len(rawRows) == 0 {
+			break
+		}
+		delay, err := r.processChunk(ctx, jobID, rawRows)
+		if err != nil {
+			return err
+		}
+		if delay > 0 {
+			return r.delay(ctx, jobID, delay)
+		}
+	}
+
+	_, err = r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		now := r.now().UnixMilli()
+		pipe.HSet(ctx, key, "status", JobStatusCompleted, "updated_at", now, "completed_at", now)
+		pipe.LRem(ctx, jobProcessingKey, 1, jobID)
+		return nil
+	})
+	if err == nil {
+		log.Info().Str("job_id", jobID).Msg("Job undangan massal selesai")
+	}
+	return err
+}
+
+// processChunk membuat undangan untuk satu kelompok baris. Baris yang terkena batas laju
+// dikembalikan ke akhir daftar baris untuk dicoba lagi; jika tidak ada baris lain yang
+// diproses, jeda hingga batas laju tersedia dikembalikan agar job ditunda.
+func (r *JobRunner) processChunk(ctx context.Context, jobID string, rawRows []string) (time.Duration, error) {
+	rows := make([]JobRow, 0, len(rawRows))
+	validRaw := make([]string, 0, len(rawRows))
+	var rowErrors []JobRowError
+	for _, raw := range rawRows {
+		var row JobRow
+		if err := json.Unmarshal([]byte(raw), &row); err != nil {
+			rowErrors = append(rowErrors, JobRowError{Status: RowStatusFailed, Error: "baris job tidak dapat dibaca"})
+			continue
+		}
+		rows = append(rows, row)
+		validRaw = append(validRaw, raw)
+	}
+
+	// IdempotencyKey per baris membuat kelompok yang diulang, misalnya karena pencatatan
+	// kemajuan gagal atau job diantrekan ulang, tidak menghitung undangannya sendiri sebagai duplikat.
+	reqs := make([]CreateInvitationRequest, len(rows))
+	for i, row := range rows {
+		reqs[i] = row.Request
+		reqs[i].IdempotencyKey = jobID + ":" + strconv.Itoa(row.Row)
+	}
+	created := 0
+	var deferred []interface{}
+	var retryAfter time.Duration
+	for i, result := range r.invitations.CreateInvitations(ctx, reqs) {
+		rowErr := JobRowError{Row: rows[i].Row, Email: rows[i].Request.Email, Status: RowStatusFailed, Error: "gagal membuat undangan"}
+		var dup *DuplicateInvitationError
+		var retryErr *RetryAfterError
+		switch {
+		case result.Err == nil, errors.As(result.Err, &dup) && dup.Replayed:
+			created++
+			continue
+		case errors.Is(result.Err, ErrRateLimited):
+			// Batas laju bersifat sementara, sehingga baris dicoba lagi alih-alih digagalkan.
+			deferred = append(deferred, validRaw[i])
+			if errors.As(result.Err, &retryErr) && (retryAfter == 0 || retryErr.RetryAfter < retryAfter) {
+				retryAfter = retryErr.RetryAfter
+			}
+			continue
+		case errors.As(result.Err, &dup):
+			rowErr.Status, rowErr.Error, rowErr.InvitationID = RowStatusDuplicate, dup.Error(), dup.InvitationID
+		case errors.Is(result.Err, ErrUnknownRole), errors.Is(result.Err, ErrRoleNotGrantable), errors.Is(result.Err, ErrShortCodesDisabled),
+			errors.Is(result.Err, ErrExpiryInPast):
+			rowErr.Status, rowErr.Error = RowStatusInvalid, result.Err.Error()
+		case errors.Is(result.Err, ErrSeatLimitExceeded):
+			rowErr.Error = result.Err.Error()
+		}
+		rowErrors = append(rowErrors, rowErr)
+	}
+
+	encodedErrors, err := encodeJobErrors(rowErrors)
+	if err != nil {
+		return 0, err
+	}
+	key := jobKey(jobID)
+	_, err = r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LTrim(ctx, jobRowsKey(jobID), int64(len(rawRows)), -1)
+		if len(deferred) > 0 {
+			pipe.RPush(ctx, jobRowsKey(jobID), deferred...)
+		}
+		pipe.HIncrBy(ctx, key, "processed", int64(created))
+		pipe.HIncrBy(ctx, key, RowStatusCreated, int64(created))
+		recordJobErrors(ctx, pipe, jobID, rowErrors, encodedErrors)
+		pipe.HSet(ctx, key, "updated_at", r.now().UnixMilli())
+		return nil
+	})
+	if err != nil || len(deferred) < len(rawRows) {
+		return 0, err
+	}
+	return max(retryAfter, minJobRetryDelay), nil
+}
+
+// delay melepaskan job dari worker dan menjadwalkannya kembali ke antrean setelah jeda.
+func (r *JobRunner) delay(ctx context.Context, jobID string, delay time.Duration) error {
+	now := r.now()
+	resumeAt := now.Add(delay).UnixMilli()
+	_, err := r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, jobKey(jobID), "status", JobStatusQueued, "resume_at", resumeAt, "updated_at", now.UnixMilli())
+		pipe.ZAdd(ctx, jobDelayedKey, redis.Z{Score: float64(resumeAt), Member: jobID})
+		pipe.LRem(ctx, jobProcessingKey, 1, jobID)
+		return nil
+	})
+	if err == nil {
+		log.Info().Str("job_id", jobID).Dur("delay", delay).Msg("Job undangan massal ditunda karena batas laju")
+	}
+	return err
+}
+
+// PromoteDelayed mengembalikan job tertunda yang sudah jatuh tempo ke antrean.
+func (r *JobRunner) PromoteDelayed(ctx context.Context) (int, error) {
+	return promoteDelayedScript.Run(ctx, r.redisClient, []string{jobDelayedKey, jobQueueKey}, r.now().UnixMilli()).Int()
+}
+
+// RequeueStale mengembalikan job yang tidak mengalami kemajuan selama jobStaleAfter ke
+// antrean, misalnya karena replika yang memprosesnya berhenti di tengah jalan.
+func (r *JobRunner) RequeueStale(ctx context.Context) (int, error) {
+	jobIDs, err := r.redisClient.LRange(ctx, jobProcessingKey, 0, -1).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	requeued := 0
+	cutoff := r.now().Add(-jobStaleAfter).UnixMilli()
+	for _, jobID := range jobIDs {
+		updatedAt, err := r.redisClient.HGet(ctx, jobKey(jobID), "updated_at").Int64()
+		if err != nil && err != redis.Nil {
+			return requeued, err
+		}
+		if err == nil && updatedAt > cutoff {
+			continue
+		}
+		if err == redis.Nil {
+			// State job sudah kedaluwarsa; hapus dari daftar tanpa mengantrekan ulang.
+			r.redisClient.LRem(ctx, jobProcessingKey, 1, jobID)
+			continue
+		}
+		n, err := requeueScript.Run(ctx, r.redisClient, []string{jobProcessingKey, jobQueueKey}, jobID).Int()
+		if err != nil {
+			return requeued, err
+		}
+		requeued += n
+	}
+	return requeued, nil
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// failCommitOnce menggagalkan transaksi pertama yang memuat perintah tertentu, untuk
+// mensimulasikan pencatatan kemajuan job yang gagal setelah undangan dibuat.
+type failCommitOnce struct {
+	command string
+	failed  bool
+}
+
+func (h *failCommitOnce) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *failCommitOnce) ProcessHook(next redis.ProcessHook) redis.ProcessHook { return next }
+
+func (h *failCommitOnce) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			if cmd.Name() == h.command && !h.failed {
+				h.failed = true
+				return &net.OpError{Op: "write", Err: errors.New("connection reset")}
+			}
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func TestJobService(t *testing.T) {
+	ctx := context.Background()
+	mockPublisher := new(MockQueuePublisher)
+	mockPublisher.On("Enqueue", mock.Anything, mock.Anything).Return(nil)
+
+	t.Run("Worker Processes Queued Job", func(t *testing.T) {
+		svc, _ := newMiniredisService(t, mockPublisher, &UUIDTokenGenerator{})
+		jobs := NewJobService(svc.redisClient)
+
+		job, err := jobs.CreateJob(ctx, "tenant-1", "inviter-1", "bulk")
+		require.NoError(t, err)
+		rows := []JobRow{
+			{Row: 0, Request: CreateInvitationRequest{Email: "a@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"}},
+			{Row: 2, Request: CreateInvitationRequest{Email: "b@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"}},
+		}
+		rejected := []JobRowError{{Row: 1, Email: "bukan-email", Status: RowStatusInvalid, Error: "email tidak valid"}}
+		require.NoError(t, jobs.AppendRows(ctx, job.ID, rows, rejected))
+		require.NoError(t, jobs.StartJob(ctx, job.ID))
+
+		queued, err := jobs.GetJob(ctx, "tenant-1", job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusQueued, queued.Status)
+		assert.Equal(t, 3, queued.Total)
+		assert.Equal(t, 1, queued.Processed)
+
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		runner := NewJobRunner(svc.redisClient, svc, 2)
+		runner.pollTimeout = 50 * time.Millisecond
+		go runner.Run(runCtx)
+
+		var done *Job
+		require.Eventually(t, func() bool {
+			done, err = jobs.GetJob(ctx, "tenant-1", job.ID)
+			return err == nil && done.Status == JobStatusCompleted
+		}, 5*time.Second, 20*time.Millisecond)
+		assert.Equal(t, 3, done.Processed)
+		assert.Equal(t, 2, done.Created)
+		assert.Equal(t, 1, done.Invalid)
+		assert.NotNil(t, done.CompletedAt)
+		assert.Equal(t, rejected, done.Errors)
+
+		page, err := svc.ListInvitations(ctx, "tenant-1", ListFilter{})
+		require.NoError(t, err)
+		assert.Len(t, page.Invitations, 2)
+	})
+
+	t.Run("Other Tenant Cannot Read Job", func(t *testing.T) {
+		svc, _ := newMiniredisService(t, mockPublisher, &UUIDTokenGenerator{})
+		jobs := NewJobService(svc.redisClient)
+		job, err := jobs.CreateJob(ctx, "tenant-1", "inviter-1", "import")
+		require.NoError(t, err)
+
+		_, err = jobs.GetJob(ctx, "tenant-2", job.ID)
+		assert.ErrorIs(t, err, ErrJobNotFound)
+	})
+
+	t.Run("Rate Limited Rows Are Retried After Delay", func(t *testing.T) {
+		svc, mr := newMiniredisService(t, mockPublisher, &UUIDTokenGenerator{})
+		WithRateLimits(RateLimits{Window: time.Minute, Tenant: 2})(svc)
+		jobs := NewJobService(svc.redisClient)
+		job, err := jobs.CreateJob(ctx, "tenant-1", "inviter-1", "bulk")
+		require.NoError(t, err)
+		var rows []JobRow
+		for i, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+			rows = append(rows, JobRow{Row: i, Request: CreateInvitationRequest{Email: email, Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"}})
+		}
+		require.NoError(t, jobs.AppendRows(ctx, job.ID, rows, nil))
+
+		runner := NewJobRunner(svc.redisClient, svc, 1)
+		runner.now = svc.now
+		require.NoError(t, runner.Process(ctx, job.ID))
+
+		delayed, err := jobs.GetJob(ctx, "tenant-1", job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusQueued, delayed.Status)
+		assert.Equal(t, 2, delayed.Created)
+		assert.Zero(t, delayed.Failed, "baris yang terkena batas laju tidak boleh digagalkan")
+		require.NotNil(t, delayed.ResumeAt)
+		assert.True(t, delayed.ResumeAt.After(fixedNow))
+		remaining, err := mr.List(jobRowsKey(job.ID))
+		require.NoError(t, err)
+		assert.Len(t, remaining, 1)
+
+		promoted, err := runner.PromoteDelayed(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, promoted, "job belum boleh dilanjutkan sebelum jedanya habis")
+
+		later := fixedNow.Add(2 * time.Minute)
+		svc.now = func() time.Time { return later }
+		runner.now = svc.now
+		promoted, err = runner.PromoteDelayed(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, promoted)
+		queue, err := mr.List(jobQueueKey)
+		require.NoError(t, err)
+		assert.Equal(t, []string{job.ID}, queue)
+
+		require.NoError(t, runner.Process(ctx, job.ID))
+		done, err := jobs.GetJob(ctx, "tenant-1", job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusCompleted, done.Status)
+		assert.Equal(t, 3, done.Created)
+		assert.Equal(t, 3, done.Processed)
+		assert.Nil(t, done.ResumeAt)
+		assert.Empty(t, done.Errors)
+	})
+
+	t.Run("Retried Chunk Counts Its Own Invitations As Created", func(t *testing.T) {
+		svc, mr := newMiniredisService(t, mockPublisher, &UUIDTokenGenerator{})
+		jobs := NewJobService(svc.redisClient)
+		job, err := jobs.CreateJob(ctx, "tenant-1", "inviter-1", "bulk")
+		require.NoError(t, err)
+		rows := []JobRow{
+			{Row: 0, Request: CreateInvitationRequest{Email: "a@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"}},
+			{Row: 1, Request: CreateInvitationRequest{Email: "b@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"}},
+			{Row: 2, Request: CreateInvitationRequest{Email: "a@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"}},
+		}
+		require.NoError(t, jobs.AppendRows(ctx, job.ID, rows, nil))
+
+		runnerClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { _ = runnerClient.Close() })
+		runnerClient.AddHook(&failCommitOnce{command: "ltrim"})
+		runner := NewJobRunner(runnerClient, svc, 1)
+
+		require.Error(t, runner.Process(ctx, job.ID), "pencatatan kemajuan kelompok harus gagal")
+		remaining, err := mr.List(jobRowsKey(job.ID))
+		require.NoError(t, err)
+		assert.Len(t, remaining, 3, "kelompok yang gagal dicatat harus diproses ulang")
+
+		require.NoError(t, runner.Process(ctx, job.ID))
+		done, err := jobs.GetJob(ctx, "tenant-1", job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusCompleted, done.Status)
+		assert.Equal(t, 2, done.Created)
+		assert.Equal(t, 1, done.Duplicate, "email yang berulang di dalam job tetap duplikat")
+		require.Len(t, done.Errors, 1)
+		assert.Equal(t, 2, done.Errors[0].Row)
+
+		page, err := svc.ListInvitations(ctx, "tenant-1", ListFilter{})
+		require.NoError(t, err)
+		assert.Len(t, page.Invitations, 2)
+	})
+
+	t.Run("Seat Limit Remains Terminal", func(t *testing.T) {
+		svc, _ := newMiniredisService(t, mockPublisher, &UUIDTokenGenerator{})
+		jobs := NewJobService(svc.redisClient)
+		svc.seatProvider, _ = NewConfigSeatProvider(1, "")
+		job, err := jobs.CreateJob(ctx, "tenant-1", "inviter-1", "bulk")
+		require.NoError(t, err)
+		rows := []JobRow{
+			{Row: 0, Request: CreateInvitationRequest{Email: "a@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"}},
+			{Row: 1, Request: CreateInvitationRequest{Email: "b@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"}},
+		}
+		require.NoError(t, jobs.AppendRows(ctx, job.ID, rows, nil))
+
+		runner := NewJobRunner(svc.redisClient, svc, 1)
+		require.NoError(t, runner.Process(ctx, job.ID))
+		done, err := jobs.GetJob(ctx, "tenant-1", job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusCompleted, done.Status)
+		assert.Equal(t, 1, done.Created)
+		assert.Equal(t, 1, done.Failed)
+	})
+
+	t.Run("Stale Job Is Requeued", func(t *testing.T) {
+		svc, mr := newMiniredisService(t, mockPublisher, &UUIDTokenGenerator{})
+		jobs := NewJobService(svc.redisClient)
+		job, err := jobs.CreateJob(ctx, "tenant-1", "inviter-1", "bulk")
+		require.NoError(t, err)
+		require.NoError(t, jobs.StartJob(ctx, job.ID))
+		// Simulasikan worker yang mengambil job lalu berhenti.
+		require.NoError(t, svc.redisClient.LMove(ctx, jobQueueKey, jobProcessingKey, "RIGHT", "LEFT").Err())
+
+		runner := NewJobRunner(svc.redisClient, svc, 1)
+		requeued, err := runner.RequeueStale(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, requeued, "job yang masih baru tidak boleh diantrekan ulang")
+
+		runner.now = func() time.Time { return time.Now().Add(jobStaleAfter + time.Minute) }
+		requeued, err = runner.RequeueStale(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, requeued)
+		queue, err := mr.List(jobQueueKey)
+		require.NoError(t, err)
+		assert.Equal(t, []string{job.ID}, queue)
+	})
+}
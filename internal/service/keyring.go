@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultKeyRefreshInterval dipakai jika RefreshingKeyring dibuat tanpa interval.
+const DefaultKeyRefreshInterval = 5 * time.Minute
+
+// DefaultVaultTimeout dipakai jika sumber kunci Transit dibuat tanpa timeout.
+const DefaultVaultTimeout = 5 * time.Second
+
+// KeyFields adalah lokasi satu jenis kunci di penyimpanan rahasia: Keys dan Active adalah
+// nama field berisi daftar kunci ("kid1:base64,kid2:base64") dan ID kunci aktif pada secret
+// KV atau berkas lokal, sedangkan Transit adalah nama kunci HMAC di secret engine Transit.
+type KeyFields struct {
+	Keys    string
+	Active  string
+	Transit string
+}
+
+// Lokasi kunci penandatangan token dan pepper hash token. Keduanya dapat disimpan dalam
+// satu secret KV atau satu berkas kunci lokal.
+var (
+	SigningKeyFields = KeyFields{Keys: "signing_keys", Active: "active_signing_key", Transit: "invitation-token-signing"}
+	PepperKeyFields  = KeyFields{Keys: "token_peppers", Active: "active_token_pepper", Transit: "invitation-token-pepper"}
+)
+
+// KeySource memuat kunci terbaru dari penyimpanan rahasia.
+type KeySource interface {
+	LoadKeys() (*StaticKeyring, error)
+}
+
+// SecretReader membaca beberapa field dari satu secret. VaultClient dari prism-common-libs
+// memenuhi interface ini untuk secret engine KV v2.
+type SecretReader interface {
+	ReadMultipleSecrets(path string, keys ...string) (map[string]string, error)
+}
+
+type vaultKeySource struct {
+	vault  SecretReader
+	path   string
+	fields KeyFields
+}
+
+// NewVaultKeySource membuat sumber kunci dari secret KV v2 di path, misalnya
+// "secret/data/prism-invitation-service".
+func NewVaultKeySource(vault SecretReader, path string, fields KeyFields) KeySource {
+	return &vaultKeySource{vault: vault, path: path, fields: fields}
+}
+
+func (s *vaultKeySource) LoadKeys() (*StaticKeyring, error) {
+	values, err := s.vault.ReadMultipleSecrets(s.path, s.fields.Keys, s.fields.Active)
+	if err != nil {
+		return nil, err
+	}
+	return keyringFromFields(values, s.fields)
+}
+
+// vaultTransitKeySource mengekspor kunci HMAC dari secret engine Transit. Kunci harus dibuat
+// dengan exportable=true; setiap versi yang belum dipensiunkan (min_decryption_version)
+// dipakai untuk verifikasi dan versi terbaru menjadi kunci aktif, sehingga rotasi cukup
+// dilakukan dengan "vault write -f transit/keys/<nama>/rotate".
+type vaultTransitKeySource struct {
+	addr       string
+	token      string
+	mount      string
+	name       string
+	httpClient *http.Client
+}
+
+// transitExport adalah respons GET /v1/{mount}/export/hmac-key/{name}.
+type transitExport struct {
+	Data struct {
+		Keys map[string]string `json:"keys"`
+	} `json:"data"`
+}
+
+// NewVaultTransitKeySource membuat sumber kunci dari Transit di mount (misalnya "transit")
+// pada server Vault addr.
+func NewVaultTransitKeySource(addr, token, mount string, fields KeyFields, timeout time.Duration) KeySource {
+	if timeout <= 0 {
+		timeout = DefaultVaultTimeout
+	}
+	return &vaultTransitKeySource{
+		addr:       strings.TrimSuffix(addr, "/"),
+		token:      token,
+		mount:      strings.Trim(mount, "/"),
+		name:       fields.Transit,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *vaultTransitKeySource) LoadKeys() (*StaticKeyring, error) {
+	endpoint := fmt.Sprintf("%s/v1/%s/export/hmac-key/%s", s.addr, s.mount, s.name)
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", s.token)
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengekspor kunci Transit '%s': %w", s.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gagal mengekspor kunci Transit '%s': status %d", s.name, resp.StatusCode)
+	}
+
+	var export transitExport
+	if err := json.NewDecoder(resp.Body).Decode(&export); err != nil {
+		return nil, fmt.Errorf("gagal membaca kunci Transit '%s': %w", s.name, err)
+	}
+	versions := make([]int, 0, len(export.Data.Keys))
+	keys := make(map[string][]byte, len(export.Data.Keys))
+	for version, encoded := range export.Data.Keys {
+		n, errVersion := strconv.Atoi(version)
+		key, errKey := base64.StdEncoding.DecodeString(encoded)
+		if errVersion != nil || errKey != nil {
+			return nil, fmt.Errorf("versi kunci Transit '%s' tidak valid: %q", s.name, version)
+		}
+		versions = append(versions, n)
+		keys[version] = key
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("kunci Transit '%s' tidak memiliki versi", s.name)
+	}
+	sort.Ints(versions)
+	return NewStaticKeyring(strconv.Itoa(versions[len(versions)-1]), keys)
+}
+
+type fileKeySource struct {
+	path   string
+	fields KeyFields
+}
+
+// NewFileKeySource membuat sumber kunci dari berkas JSON lokal untuk pengembangan. Isi berkas
+// berupa objek dengan field yang sama seperti secret Vault, misalnya
+// {"signing_keys": "dev:base64", "active_signing_key": "dev"}.
+func NewFileKeySource(path string, fields KeyFields) KeySource {
+	return &fileKeySource{path: path, fields: fields}
+}
+
+func (s *fileKeySource) LoadKeys() (*StaticKeyring, error) {
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca berkas kunci: %w", err)
+	}
+	var values map[string]string
+	if err := json.Unmarshal(content, &values); err != nil {
+		return nil, fmt.Errorf("gagal membaca berkas kunci '%s': %w", s.path, err)
+	}
+	return keyringFromFields(values, s.fields)
+}
+
+func keyringFromFields(values map[string]string, fields KeyFields) (*StaticKeyring, error) {
+	keys, err := ParseKeys(values[fields.Keys])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fields.Keys, err)
+	}
+	keyring, err := NewStaticKeyring(values[fields.Active], keys)
+	if err != nil {
+		return nil, fmt.Errorf("%s/%s: %w", fields.Keys, fields.Active, err)
+	}
+	return keyring, nil
+}
+
+// RefreshingKeyring adalah Keyring di memori yang dimuat ulang dari KeySource secara berkala.
+// Semua kunci dalam sumber dapat dipakai untuk verifikasi, sehingga kunci baru dapat
+// ditambahkan sebelum diaktifkan dan kunci lama dihapus setelah tokennya kedaluwarsa.
+type RefreshingKeyring struct {
+	source   KeySource
+	interval time.Duration
+
+	mu      sync.RWMutex
+	current *StaticKeyring
+}
+
+// NewRefreshingKeyring memuat kunci dari source dan gagal jika kunci awal tidak tersedia.
+func NewRefreshingKeyring(source KeySource, interval time.Duration) (*RefreshingKeyring, error) {
+	if interval <= 0 {
+		interval = DefaultKeyRefreshInterval
+	}
+	k := &RefreshingKeyring{source: source, interval: interval}
+	if err := k.Refresh(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Refresh memuat ulang kunci dari sumber. Jika gagal, kunci sebelumnya tetap dipakai.
+func (k *RefreshingKeyring) Refresh() error {
+	keyring, err := k.source.LoadKeys()
+	if err != nil {
+		return fmt.Errorf("gagal memuat keyring: %w", err)
+	}
+	k.mu.Lock()
+	k.current = keyring
+	k.mu.Unlock()
+	return nil
+}
+
+// Run memuat ulang kunci setiap interval hingga ctx dibatalkan.
+func (k *RefreshingKeyring) Run(ctx context.Context) {
+	ticker := time.NewTicker(k.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Refresh(); err != nil {
+				log.Error().Err(err).Msg("Gagal memperbarui keyring, kunci sebelumnya tetap dipakai")
+			}
+		}
+	}
+}
+
+func (k *RefreshingKeyring) ActiveKey() (string, []byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current.ActiveKey()
+}
+
+func (k *RefreshingKeyring) Key(kid string) ([]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current.Key(kid)
+}
+
+func (k *RefreshingKeyring) KeyIDs() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current.KeyIDs()
+}
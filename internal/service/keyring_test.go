@@ -0,0 +1,124 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSecretReader meniru secret KV Vault yang dapat diubah selama test.
+type fakeSecretReader struct {
+	mu     sync.Mutex
+	values map[string]string
+	err    error
+}
+
+func (f *fakeSecretReader) set(values map[string]string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values, f.err = values, err
+}
+
+func (f *fakeSecretReader) ReadMultipleSecrets(_ string, keys ...string) (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	result := make(map[string]string)
+	for _, key := range keys {
+		if value, ok := f.values[key]; ok {
+			result[key] = value
+		}
+	}
+	return result, nil
+}
+
+func TestRefreshingKeyring(t *testing.T) {
+	keyA := []byte("0123456789abcdef0123456789abcdef")
+	keyB := []byte("fedcba9876543210fedcba9876543210")
+	encodedA := base64.StdEncoding.EncodeToString(keyA)
+	encodedB := base64.StdEncoding.EncodeToString(keyB)
+
+	t.Run("Vault KV Rotation", func(t *testing.T) {
+		vault := &fakeSecretReader{values: map[string]string{"signing_keys": "a:" + encodedA, "active_signing_key": "a"}}
+		keyring, err := NewRefreshingKeyring(NewVaultKeySource(vault, "secret/data/invitation", SigningKeyFields), time.Minute)
+		require.NoError(t, err)
+		g := NewSignedTokenGenerator(keyring, false)
+		g.now = func() time.Time { return fixedNow }
+		token, err := g.Generate(TokenClaims{InvitationID: "inv-1", TenantID: "tenant-1", ExpiresAt: fixedNow.Add(time.Hour)})
+		require.NoError(t, err)
+
+		// Kunci baru diaktifkan sementara kunci lama tetap dipakai untuk verifikasi.
+		vault.set(map[string]string{"signing_keys": "a:" + encodedA + ",b:" + encodedB, "active_signing_key": "b"}, nil)
+		require.NoError(t, keyring.Refresh())
+		kid, _, err := keyring.ActiveKey()
+		require.NoError(t, err)
+		assert.Equal(t, "b", kid)
+		_, err = g.Verify(token)
+		assert.NoError(t, err)
+
+		// Kegagalan Vault atau secret yang tidak valid tidak menghapus kunci yang sedang dipakai.
+		vault.set(nil, errors.New("vault sealed"))
+		assert.Error(t, keyring.Refresh())
+		vault.set(map[string]string{"signing_keys": "a:" + encodedA, "active_signing_key": "missing"}, nil)
+		assert.Error(t, keyring.Refresh())
+		_, err = g.Verify(token)
+		assert.NoError(t, err)
+
+		vault.set(map[string]string{"signing_keys": "b:" + encodedB, "active_signing_key": "b"}, nil)
+		require.NoError(t, keyring.Refresh())
+		_, err = g.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Initial Load Fails", func(t *testing.T) {
+		vault := &fakeSecretReader{err: errors.New("permission denied")}
+		_, err := NewRefreshingKeyring(NewVaultKeySource(vault, "secret/data/invitation", SigningKeyFields), time.Minute)
+		assert.Error(t, err)
+	})
+
+	t.Run("Vault Transit", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/transit/export/hmac-key/invitation-token-signing", r.URL.Path)
+			assert.Equal(t, "vault-token", r.Header.Get("X-Vault-Token"))
+			fmt.Fprintf(w, `{"data":{"name":"invitation-token-signing","keys":{"1":%q,"2":%q}}}`, encodedA, encodedB)
+		}))
+		defer server.Close()
+
+		keyring, err := NewRefreshingKeyring(NewVaultTransitKeySource(server.URL, "vault-token", "transit", SigningKeyFields, time.Second), time.Minute)
+		require.NoError(t, err)
+		kid, key, err := keyring.ActiveKey()
+		require.NoError(t, err)
+		assert.Equal(t, "2", kid)
+		assert.Equal(t, keyB, key)
+		key, err = keyring.Key("1")
+		require.NoError(t, err)
+		assert.Equal(t, keyA, key)
+	})
+
+	t.Run("Local File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "keys.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"signing_keys":"dev:`+encodedA+`","active_signing_key":"dev"}`), 0o600))
+		keyring, err := NewRefreshingKeyring(NewFileKeySource(path, SigningKeyFields), time.Minute)
+		require.NoError(t, err)
+		kid, key, err := keyring.ActiveKey()
+		require.NoError(t, err)
+		assert.Equal(t, "dev", kid)
+		assert.Equal(t, keyA, key)
+
+		_, err = NewRefreshingKeyring(NewFileKeySource(path, PepperKeyFields), time.Minute)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "active_token_pepper", "error harus menyebut field pepper, bukan kunci penandatangan")
+	})
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultLinkTemplate adalah tautan penerimaan undangan jika WithLinkTemplates tidak diberikan.
+const DefaultLinkTemplate = "https://app.prismerp.com/accept-invitation?token={token}"
+
+// linkTokenPlaceholder diganti dengan token undangan saat tautan dibuat.
+const linkTokenPlaceholder = "{token}"
+
+// LinkTemplates menyusun tautan penerimaan undangan dari template URL berisi {token}, dengan
+// template per tenant untuk domain white-label, path berbeda atau parameter tambahan seperti
+// tag UTM, misalnya "https://join.acme.example/invite/{token}?utm_source=email".
+type LinkTemplates struct {
+	defaultTemplate string
+	tenants         map[string]string
+}
+
+// NewLinkTemplates memvalidasi template default dan template per tenant. Setiap template
+// harus berisi {token} dan menghasilkan URL HTTPS absolut.
+func NewLinkTemplates(defaultTemplate string, tenants map[string]string) (*LinkTemplates, error) {
+	if err := validateLinkTemplate(defaultTemplate); err != nil {
+		return nil, err
+	}
+	for tenantID, template := range tenants {
+		if err := validateLinkTemplate(template); err != nil {
+			return nil, fmt.Errorf("template tautan tenant '%s': %w", tenantID, err)
+		}
+	}
+	return &LinkTemplates{defaultTemplate: defaultTemplate, tenants: tenants}, nil
+}
+
+// WithLinkTemplates mengatur template tautan penerimaan yang dikirim di email undangan.
+func WithLinkTemplates(links *LinkTemplates) Option {
+	return func(s *invitationService) {
+		s.links = links
+	}
+}
+
+// ParseLinkTemplates membaca template tautan per tenant dalam format
+// "tenant-a=https://join.acme.example/{token},tenant-b=https://...". Koma di dalam URL harus
+// ditulis sebagai %2C.
+func ParseLinkTemplates(overrides string) (map[string]string, error) {
+	templates := make(map[string]string)
+	for _, entry := range strings.Split(overrides, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		tenantID, template, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(tenantID) == "" {
+			return nil, fmt.Errorf("format template tautan undangan tidak valid: %q", entry)
+		}
+		templates[strings.TrimSpace(tenantID)] = strings.TrimSpace(template)
+	}
+	return templates, nil
+}
+
+// Link membuat tautan penerimaan untuk token undangan tenantID.
+func (l *LinkTemplates) Link(tenantID, token string) (string, error) {
+	template, ok := l.tenants[tenantID]
+	if !ok {
+		template = l.defaultTemplate
+	}
+	return expandLinkTemplate(template, token)
+}
+
+func validateLinkTemplate(template string) error {
+	if !strings.Contains(template, linkTokenPlaceholder) {
+		return fmt.Errorf("template tautan undangan %q tidak berisi %s", template, linkTokenPlaceholder)
+	}
+	_, err := expandLinkTemplate(template, "token")
+	return err
+}
+
+// expandLinkTemplate mengganti {token} dan memastikan hasilnya URL HTTPS absolut, sehingga
+// token tidak pernah dikirim melalui tautan yang tidak terenkripsi.
+func expandLinkTemplate(template, token string) (string, error) {
+	link := strings.ReplaceAll(template, linkTokenPlaceholder, url.QueryEscape(token))
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("tautan undangan tidak valid: %w", err)
+	}
+	if parsed.Scheme != "https" || parsed.Host == "" {
+		return "", fmt.Errorf("tautan undangan %q harus berupa URL HTTPS absolut", template)
+	}
+	return link, nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkTemplates(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Tenant Overrides", func(t *testing.T) {
+		tenants, err := ParseLinkTemplates("acme=https://join.acme.example/invite/{token}?utm_source=email&utm_campaign=onboarding, staging=https://staging.prismerp.com/accept-invitation?token={token}")
+		require.NoError(t, err)
+		links, err := NewLinkTemplates(DefaultLinkTemplate, tenants)
+		require.NoError(t, err)
+
+		link, err := links.Link("acme", "abc.def-123")
+		require.NoError(t, err)
+		assert.Equal(t, "https://join.acme.example/invite/abc.def-123?utm_source=email&utm_campaign=onboarding", link)
+		link, err = links.Link("staging", "K7QF-9M2X")
+		require.NoError(t, err)
+		assert.Equal(t, "https://staging.prismerp.com/accept-invitation?token=K7QF-9M2X", link)
+		link, err = links.Link("other", "abc")
+		require.NoError(t, err)
+		assert.Equal(t, "https://app.prismerp.com/accept-invitation?token=abc", link)
+	})
+
+	t.Run("Rejects Non HTTPS Templates", func(t *testing.T) {
+		for _, template := range []string{
+			"http://app.prismerp.com/accept-invitation?token={token}",
+			"/accept-invitation?token={token}",
+			"https:///accept-invitation?token={token}",
+			"https://app.prismerp.com/accept-invitation",
+		} {
+			_, err := NewLinkTemplates(template, nil)
+			assert.Error(t, err, template)
+			_, err = NewLinkTemplates(DefaultLinkTemplate, map[string]string{"acme": template})
+			assert.Error(t, err, template)
+		}
+		_, err := ParseLinkTemplates("https://join.acme.example/{token}")
+		assert.Error(t, err)
+	})
+
+	t.Run("Notification Uses Tenant Link", func(t *testing.T) {
+		var sentLink string
+		mockPublisher := new(MockQueuePublisher)
+		mockPublisher.On("Enqueue", ctx, mock.Anything).Run(func(args mock.Arguments) {
+			sentLink = args.Get(1).(client.NotificationPayload).TemplateData["InvitationLink"].(string)
+		}).Return(nil).Once()
+		svc, _ := newMiniredisService(t, mockPublisher, &MockTokenGenerator{TokenToReturn: "tenant-token"})
+		links, err := NewLinkTemplates(DefaultLinkTemplate, map[string]string{"acme": "https://join.acme.example/invite/{token}"})
+		require.NoError(t, err)
+		WithLinkTemplates(links)(svc)
+
+		_, err = svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "user@acme.example", Role: "viewer", TenantID: "acme", InviterID: "inviter-1"})
+		require.NoError(t, err)
+		assert.Equal(t, "https://join.acme.example/invite/tenant-token", sentLink)
+	})
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// rateLimitedTotal menghitung percobaan pembuatan undangan yang ditolak batas laju per cakupan.
+var rateLimitedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "prism_invitation_rate_limited_total",
+	Help: "Jumlah percobaan pembuatan undangan yang ditolak karena batas laju, berdasarkan cakupan (tenant, inviter, domain).",
+}, []string{"scope"})
+
+// invalidTokenAttempts menghitung token undangan tidak valid yang diterima endpoint publik.
+// Lonjakan nilainya menandakan percobaan menebak token, misalnya dengan aturan alert
+// rate(prism_invitation_invalid_token_attempts_total[5m]) di atas batas normal.
+var invalidTokenAttempts = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "prism_invitation_invalid_token_attempts_total",
+	Help: "Jumlah percobaan dengan token undangan tidak valid pada endpoint publik.",
+})
+
+// legacyTokenLookups menghitung token undangan yang ditemukan dengan hash sha256 tanpa pepper.
+// Setelah nilainya berhenti bertambah selama masa berlaku undangan terpanjang, penerimaan hash
+// lama dapat dimatikan.
+var legacyTokenLookups = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "prism_invitation_legacy_token_lookups_total",
+	Help: "Jumlah token undangan yang ditemukan dengan hash lama tanpa pepper.",
+})
+
+// tokenLockouts menghitung penguncian yang dipasang TokenGuard per cakupan.
+var tokenLockouts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "prism_invitation_token_lockouts_total",
+	Help: "Jumlah penguncian endpoint token undangan karena terlalu banyak token tidak valid, berdasarkan cakupan (ip, global).",
+}, []string{"scope"})
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/client"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// outboxMessagesKey adalah hash berisi isi pesan outbox, dengan field berupa ID pesan.
+	outboxMessagesKey = "invitation_outbox:messages"
+	// outboxPendingKey adalah sorted set ID pesan yang belum terkirim, dengan skor berupa
+	// waktu percobaan berikutnya (Unix milidetik).
+	outboxPendingKey = "invitation_outbox:pending"
+	// outboxDeadKey menyimpan pesan yang gagal terkirim setelah seluruh percobaan habis.
+	outboxDeadKey = "invitation_outbox:dead"
+
+	// outboxDispatchGrace adalah jeda sebelum relay boleh mengambil pesan baru. Selama jeda
+	// ini service mencoba mengirim pesan secara langsung, sehingga relay hanya menangani
+	// pesan yang pengiriman langsungnya gagal atau terputus.
+	outboxDispatchGrace = 30 * time.Second
+
+	// maxDeadLetters dan deadLetterTTL membatasi jumlah dan umur pesan gagal yang disimpan
+	// untuk diperiksa operator. TTL diperbarui setiap kali pesan baru masuk.
+	maxDeadLetters = 1000
+	deadLetterTTL  = 7 * 24 * time.Hour
+
+	// DefaultOutboxPollInterval dan DefaultOutboxMaxAttempts dipakai jika relay tidak dikonfigurasi.
+	DefaultOutboxPollInterval = time.Second
+	DefaultOutboxMaxAttempts  = 10
+
+	outboxBatchSize   = 50
+	outboxBaseBackoff = 5 * time.Second
+	outboxMaxBackoff  = 10 * time.Minute
+)
+
+// OutboxMessage adalah notifikasi yang menunggu dikirim ke QueuePublisher. Payload berisi
+// tautan undangan dengan token mentah, sehingga pesan dihapus segera setelah terkirim dan
+// tautan serta kode undangan dibuang sebelum pesan dipindahkan ke dead letter.
+type OutboxMessage struct {
+	ID           string                     `json:"id"`
+	InvitationID string                     `json:"invitationID"`
+	Payload      client.NotificationPayload `json:"payload"`
+	Attempts     int                        `json:"attempts"`
+	CreatedAt    time.Time                  `json:"createdAt"`
+	LastError    string                     `json:"lastError,omitempty"`
+}
+
+// claimOutboxScript mengambil pesan yang sudah jatuh tempo dan langsung menunda percobaan
+// berikutnya selama lease, sehingga beberapa replika relay tidak mengirim pesan yang sama.
+//
+// KEYS[1] = sorted set pesan tertunda.
+// ARGV[1] = waktu sekarang, ARGV[2] = batas lease (keduanya Unix milidetik), ARGV[3] = jumlah maksimum.
+var claimOutboxScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+for _, id in ipairs(ids) do
+	redis.call('ZADD', KEYS[1], ARGV[2], id)
+end
+return ids
+`)
+
+// newOutboxMessage menyiapkan pesan outbox untuk email undangan.
+func (s *invitationService) newOutboxMessage(token string, data *InvitationData) (*OutboxMessage, []byte, error) {
+	link, err := s.links.Link(data.TenantID, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	msg := &OutboxMessage{
+		ID:           s.newID(),
+		InvitationID: data.ID,
+		Payload:      invitationNotification(link, token, data),
+		CreatedAt:    s.now().UTC(),
+	}
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return msg, encoded, nil
+}
+
+// writeOutbox menambahkan pesan ke outbox di dalam transaksi pemanggil, sehingga undangan
+// dan notifikasinya selalu tersimpan bersama.
+func writeOutbox(ctx context.Context, pipe redis.Pipeliner, msg *OutboxMessage, encoded []byte, dueAt time.Time) {
+	pipe.HSet(ctx, outboxMessagesKey, msg.ID, encoded)
+	pipe.ZAdd(ctx, outboxPendingKey, redis.Z{Score: float64(dueAt.UnixMilli()), Member: msg.ID})
+}
+
+// ackOutbox menghapus pesan yang sudah diterima broker dari outbox.
+func ackOutbox(ctx context.Context, rdb redis.Cmdable, messageIDs ...string) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+	members := make([]interface{}, len(messageIDs))
+	for i, id := range messageIDs {
+		members[i] = id
+	}
+	_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, outboxPendingKey, members...)
+		pipe.HDel(ctx, outboxMessagesKey, messageIDs...)
+		return nil
+	})
+	return err
+}
+
+// dispatchOutbox mencoba mengirim pesan yang baru ditulis secara langsung. Kegagalan tidak
+// dikembalikan ke pemanggil karena pesan tetap tersimpan dan akan dikirim ulang oleh relay.
+func (s *invitationService) dispatchOutbox(ctx context.Context, msgs ...*OutboxMessage) {
+	acked := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		if err := s.queuePublisher.Enqueue(ctx, msg.Payload); err != nil {
+			// Sisa pesan tidak dicoba karena broker kemungkinan besar sedang bermasalah.
+			log.Warn().Err(err).Str("invitation_id", msg.InvitationID).Str("message_id", msg.ID).Int("remaining", len(msgs)-len(acked)).Msg("Email undangan belum terkirim, akan dicoba ulang oleh relay outbox")
+			break
+		}
+		acked = append(acked, msg.ID)
+	}
+	if err := ackOutbox(ctx, s.redisClient, acked...); err != nil {
+		// Relay akan mengirim ulang pesan ini; notification-service menerima pengiriman ganda.
+		log.Warn().Err(err).Strs("message_ids", acked).Msg("Gagal menghapus pesan outbox yang sudah terkirim")
+	}
+}
+
+// OutboxRelay secara berkala mengirim pesan outbox yang tertunda ke QueuePublisher dan
+// mengulanginya dengan backoff eksponensial selama broker tidak tersedia.
+type OutboxRelay struct {
+	redisClient *redis.Client
+	publisher   client.QueuePublisher
+	interval    time.Duration
+	maxAttempts int
+	lease       time.Duration
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+	now         func() time.Time
+}
+
+// NewOutboxRelay membuat relay yang memeriksa outbox setiap interval. Pesan yang gagal
+// sebanyak maxAttempts dipindahkan ke daftar dead letter.
+func NewOutboxRelay(redisClient *redis.Client, publisher client.QueuePublisher, interval time.Duration, maxAttempts int) *OutboxRelay {
+	if interval <= 0 {
+		interval = DefaultOutboxPollInterval
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultOutboxMaxAttempts
+	}
+	return &OutboxRelay{
+		redisClient: redisClient,
+		publisher:   publisher,
+		interval:    interval,
+		maxAttempts: maxAttempts,
+		lease:       outboxDispatchGrace,
+		baseBackoff: outboxBaseBackoff,
+		maxBackoff:  outboxMaxBackoff,
+		now:         time.Now,
+	}
+}
+
+// Run menjalankan relay hingga ctx dibatalkan.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if sent, err := r.Drain(ctx); err != nil {
+				log.Error().Err(err).Msg("Gagal memproses outbox undangan")
+			} else if sent > 0 {
+				log.Info().Int("count", sent).Msg("Pesan outbox undangan terkirim")
+			}
+		}
+	}
+}
+
+// Drain mengirim pesan outbox yang sudah jatuh tempo dan mengembalikan jumlah yang terkirim.
+func (r *OutboxRelay) Drain(ctx context.Context) (int, error) {
+	sent := 0
+	for {
+		now := r.now()
+		ids, err := claimOutboxScript.Run(ctx, r.redisClient, []string{outboxPendingKey},
+			now.UnixMilli(), now.Add(r.lease).UnixMilli(), outboxBatchSize).StringSlice()
+		if err != nil {
+			return sent, fmt.Errorf("gagal mengambil pesan outbox: %w", err)
+		}
+		if len(ids) == 0 {
+			return sent, nil
+		}
+
+		bodies, err := r.redisClient.HMGet(ctx, outboxMessagesKey, ids...).Result()
+		if err != nil {
+			return sent, err
+		}
+		for i, body := range bodies {
+			if r.deliver(ctx, ids[i], body) {
+				sent++
+			}
+		}
+		if len(ids) < outboxBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+// deliver mengirim satu pesan dan mengembalikan true jika broker menerimanya.
+func (r *OutboxRelay) deliver(ctx context.Context, messageID string, body interface{}) bool {
+	raw, ok := body.(string)
+	if !ok {
+		// Isi pesan sudah dihapus oleh pengirim lain; bersihkan entri yang tersisa.
+		r.redisClient.ZRem(ctx, outboxPendingKey, messageID)
+		return false
+	}
+	var msg OutboxMessage
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		log.Error().Err(err).Str("message_id", messageID).Msg("Pesan outbox tidak dapat dibaca, dipindahkan ke dead letter")
+		// Isi pesan mungkin berisi token mentah dan tidak dapat disaring, sehingga tidak disimpan.
+		unreadable, _ := json.Marshal(OutboxMessage{ID: messageID, LastError: fmt.Sprintf("isi pesan tidak dapat dibaca: %v", err)})
+		r.deadLetter(ctx, messageID, string(unreadable))
+		return false
+	}
+
+	publishErr := r.publisher.Enqueue(ctx, msg.Payload)
+	if publishErr == nil {
+		if err := ackOutbox(ctx, r.redisClient, messageID); err != nil {
+			log.Warn().Err(err).Str("message_id", messageID).Msg("Gagal menghapus pesan outbox yang sudah terkirim")
+		}
+		return true
+	}
+
+	msg.Attempts++
+	msg.LastError = publishErr.Error()
+	if msg.Attempts >= r.maxAttempts {
+		log.Error().Err(publishErr).Str("message_id", messageID).Str("invitation_id", msg.InvitationID).Int("attempts", msg.Attempts).Msg("Email undangan gagal terkirim setelah seluruh percobaan, dipindahkan ke dead letter")
+		redactNotification(&msg.Payload)
+		encoded, err := json.Marshal(msg)
+		if err != nil {
+			log.Error().Err(err).Str("message_id", messageID).Msg("Gagal menyimpan status pesan outbox")
+			return false
+		}
+		r.deadLetter(ctx, messageID, string(encoded))
+		return false
+	}
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		log.Error().Err(err).Str("message_id", messageID).Msg("Gagal menyimpan status pesan outbox")
+		return false
+	}
+
+	nextAttempt := r.now().Add(r.backoff(msg.Attempts))
+	_, err = r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, outboxMessagesKey, messageID, encoded)
+		pipe.ZAdd(ctx, outboxPendingKey, redis.Z{Score: float64(nextAttempt.UnixMilli()), Member: messageID})
+		return nil
+	})
+	if err != nil {
+		log.Error().Err(err).Str("message_id", messageID).Msg("Gagal menjadwalkan ulang pesan outbox")
+	}
+	log.Warn().Err(publishErr).Str("message_id", messageID).Int("attempts", msg.Attempts).Time("next_attempt", nextAttempt).Msg("Pengiriman email undangan gagal, dijadwalkan ulang")
+	return false
+}
+
+// backoff menghitung jeda sebelum percobaan berikutnya, berlipat dua untuk setiap kegagalan.
+func (r *OutboxRelay) backoff(attempts int) time.Duration {
+	delay := r.baseBackoff
+	for i := 1; i < attempts && delay < r.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.maxBackoff {
+		delay = r.maxBackoff
+	}
+	return delay
+}
+
+// deadLetter memindahkan pesan dari outbox ke daftar dead letter secara atomik.
+func (r *OutboxRelay) deadLetter(ctx context.Context, messageID, body string) {
+	_, err := r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, outboxPendingKey, messageID)
+		pipe.HDel(ctx, outboxMessagesKey, messageID)
+		pipe.LPush(ctx, outboxDeadKey, body)
+		pipe.LTrim(ctx, outboxDeadKey, 0, maxDeadLetters-1)
+		pipe.Expire(ctx, outboxDeadKey, deadLetterTTL)
+		return nil
+	})
+	if err != nil {
+		log.Error().Err(err).Str("message_id", messageID).Msg("Gagal memindahkan pesan outbox ke dead letter")
+	}
+}
+
+// redactNotification membuang tautan dan kode undangan yang berisi token mentah. Undangan
+// yang emailnya gagal terkirim dapat dikirim ulang dengan token baru melalui ResendInvitation.
+func redactNotification(payload *client.NotificationPayload) {
+	delete(payload.TemplateData, "InvitationLink")
+	delete(payload.TemplateData, "InvitationCode")
+}
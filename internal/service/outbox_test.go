@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxRelay(t *testing.T) {
+	ctx := context.Background()
+	brokerDown := errors.New("broker down")
+
+	t.Run("Broker Outage Delays Email", func(t *testing.T) {
+		mockPublisher := new(MockQueuePublisher)
+		mockPublisher.On("Enqueue", ctx, mock.Anything).Return(brokerDown).Twice()
+		mockPublisher.On("Enqueue", ctx, mock.MatchedBy(func(p client.NotificationPayload) bool {
+			return p.Recipient == "user@example.com"
+		})).Return(nil).Once()
+		svc, mr := newMiniredisService(t, mockPublisher, &MockTokenGenerator{TokenToReturn: "outbox-token"})
+
+		_, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "user@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+		pending, err := mr.ZMembers(outboxPendingKey)
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+
+		relay := NewOutboxRelay(svc.redisClient, mockPublisher, time.Second, 5)
+		clock := fixedNow
+		relay.now = func() time.Time { return clock }
+
+		// Pesan baru belum diambil relay selama jeda pengiriman langsung.
+		sent, err := relay.Drain(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, sent)
+
+		clock = clock.Add(outboxDispatchGrace)
+		sent, err = relay.Drain(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, sent)
+
+		var msg OutboxMessage
+		require.NoError(t, json.Unmarshal([]byte(mr.HGet(outboxMessagesKey, pending[0])), &msg))
+		assert.Equal(t, 1, msg.Attempts)
+		assert.Equal(t, brokerDown.Error(), msg.LastError)
+
+		clock = clock.Add(outboxBaseBackoff)
+		sent, err = relay.Drain(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, sent)
+
+		assert.False(t, mr.Exists(outboxPendingKey))
+		assert.False(t, mr.Exists(outboxMessagesKey))
+		mockPublisher.AssertExpectations(t)
+	})
+
+	t.Run("Dead Letter After Max Attempts", func(t *testing.T) {
+		mockPublisher := new(MockQueuePublisher)
+		mockPublisher.On("Enqueue", ctx, mock.Anything).Return(brokerDown)
+		svc, mr := newMiniredisService(t, mockPublisher, &MockTokenGenerator{TokenToReturn: "dead-token"})
+
+		_, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "user@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+
+		relay := NewOutboxRelay(svc.redisClient, mockPublisher, time.Second, 2)
+		clock := fixedNow.Add(outboxDispatchGrace)
+		relay.now = func() time.Time { return clock }
+
+		for i := 0; i < 2; i++ {
+			_, err := relay.Drain(ctx)
+			require.NoError(t, err)
+			clock = clock.Add(outboxMaxBackoff)
+		}
+
+		assert.False(t, mr.Exists(outboxPendingKey))
+		dead, err := mr.List(outboxDeadKey)
+		require.NoError(t, err)
+		require.Len(t, dead, 1)
+		assert.Contains(t, dead[0], "user@example.com")
+		assert.NotContains(t, dead[0], "dead-token")
+		assert.Equal(t, deadLetterTTL, mr.TTL(outboxDeadKey))
+	})
+}
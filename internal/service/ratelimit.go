@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Cakupan batas laju pembuatan undangan, juga dipakai sebagai label metrik.
+const (
+	rateScopeTenant  = "tenant"
+	rateScopeInviter = "inviter"
+	rateScopeDomain  = "domain"
+)
+
+// RateLimits mengatur jumlah maksimum undangan yang boleh dibuat dalam jendela geser Window,
+// masing-masing per tenant, per pengundang, dan per domain email penerima di sebuah tenant.
+// Batas bernilai 0 dinonaktifkan.
+type RateLimits struct {
+	Window  time.Duration
+	Tenant  int
+	Inviter int
+	Domain  int
+}
+
+// WithRateLimits mengaktifkan batas laju pembuatan undangan.
+func WithRateLimits(limits RateLimits) Option {
+	return func(s *invitationService) {
+		s.rateLimits = limits
+	}
+}
+
+// rateLimitScript menerapkan sliding window log di beberapa key sekaligus. Percobaan hanya
+// dicatat jika semua batas masih terpenuhi, sehingga percobaan yang ditolak tidak ikut
+// menghabiskan kuota.
+//
+// KEYS = sorted set percobaan per cakupan.
+// ARGV[1] = waktu sekarang (Unix milidetik), ARGV[2] = panjang jendela (milidetik),
+// ARGV[3] = ID percobaan, ARGV[3+i] = batas untuk KEYS[i].
+// Mengembalikan {0, 0} jika diizinkan, atau {i, jeda milidetik} untuk batas pertama yang terlampaui.
+var rateLimitScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+for i, key in ipairs(KEYS) do
+	redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+	if redis.call('ZCARD', key) >= tonumber(ARGV[3 + i]) then
+		local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+		return {i, tonumber(oldest[2]) + window - now}
+	end
+end
+for _, key in ipairs(KEYS) do
+	redis.call('ZADD', key, now, ARGV[3])
+	redis.call('PEXPIRE', key, window)
+end
+return {0, 0}
+`)
+
+func rateLimitKey(scope, id string) string {
+	return fmt.Sprintf("invitation_rate:%s:%s", scope, id)
+}
+
+// emailDomain mengembalikan domain email yang dinormalisasi.
+func emailDomain(email string) string {
+	email = NormalizeEmail(email)
+	return email[strings.LastIndex(email, "@")+1:]
+}
+
+// checkRateLimit mencatat satu percobaan pembuatan undangan dan mengembalikan RetryAfterError
+// berisi ErrRateLimited jika salah satu batas terlampaui.
+func (s *invitationService) checkRateLimit(ctx context.Context, req CreateInvitationRequest) error {
+	limits := s.rateLimits
+	if limits.Window <= 0 {
+		return nil
+	}
+
+	var scopes, keys []string
+	args := []interface{}{s.now().UnixMilli(), limits.Window.Milliseconds(), uuid.NewString()}
+	add := func(scope, id string, limit int) {
+		if limit > 0 {
+			scopes = append(scopes, scope)
+			keys = append(keys, rateLimitKey(scope, id))
+			args = append(args, limit)
+		}
+	}
+	add(rateScopeTenant, req.TenantID, limits.Tenant)
+	add(rateScopeInviter, req.TenantID+":"+req.InviterID, limits.Inviter)
+	add(rateScopeDomain, req.TenantID+":"+emailDomain(req.Email), limits.Domain)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	result, err := rateLimitScript.Run(ctx, s.redisClient, keys, args...).Int64Slice()
+	if err != nil {
+		return fmt.Errorf("gagal memeriksa batas laju undangan: %w", err)
+	}
+	if result[0] == 0 {
+		return nil
+	}
+	rateLimitedTotal.WithLabelValues(scopes[result[0]-1]).Inc()
+	return &RetryAfterError{Err: ErrRateLimited, RetryAfter: time.Duration(result[1]) * time.Millisecond}
+}
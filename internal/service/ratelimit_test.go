@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvitationService_RateLimits(t *testing.T) {
+	ctx := context.Background()
+	newReq := func(inviterID, email string) CreateInvitationRequest {
+		return CreateInvitationRequest{Email: email, Role: "viewer", TenantID: "tenant-1", InviterID: inviterID}
+	}
+
+	t.Run("Per Inviter Sliding Window", func(t *testing.T) {
+		svc, _ := newDeliveringService(t, &UUIDTokenGenerator{})
+		svc.rateLimits = RateLimits{Window: time.Hour, Inviter: 2}
+		clock := fixedNow
+		svc.now = func() time.Time { return clock }
+		rejectedBefore := testutil.ToFloat64(rateLimitedTotal.WithLabelValues(rateScopeInviter))
+
+		_, err := svc.CreateInvitation(ctx, newReq("inviter-1", "a@example.com"))
+		require.NoError(t, err)
+		clock = clock.Add(10 * time.Minute)
+		_, err = svc.CreateInvitation(ctx, newReq("inviter-1", "b@example.com"))
+		require.NoError(t, err)
+
+		_, err = svc.CreateInvitation(ctx, newReq("inviter-1", "c@example.com"))
+		require.ErrorIs(t, err, ErrRateLimited)
+		var retryErr *RetryAfterError
+		require.ErrorAs(t, err, &retryErr)
+		assert.Equal(t, 50*time.Minute, retryErr.RetryAfter)
+		assert.Equal(t, rejectedBefore+1, testutil.ToFloat64(rateLimitedTotal.WithLabelValues(rateScopeInviter)))
+
+		_, err = svc.CreateInvitation(ctx, newReq("inviter-2", "c@example.com"))
+		require.NoError(t, err, "pengundang lain memiliki kuota sendiri")
+
+		// Percobaan pertama keluar dari jendela sehingga kuota pulih satu.
+		clock = fixedNow.Add(time.Hour)
+		_, err = svc.CreateInvitation(ctx, newReq("inviter-1", "d@example.com"))
+		require.NoError(t, err)
+		_, err = svc.CreateInvitation(ctx, newReq("inviter-1", "e@example.com"))
+		assert.ErrorIs(t, err, ErrRateLimited)
+	})
+
+	t.Run("Per Recipient Domain", func(t *testing.T) {
+		svc, _ := newDeliveringService(t, &UUIDTokenGenerator{})
+		svc.rateLimits = RateLimits{Window: time.Hour, Tenant: 10, Domain: 1}
+
+		_, err := svc.CreateInvitation(ctx, newReq("inviter-1", "a@victim.example"))
+		require.NoError(t, err)
+		_, err = svc.CreateInvitation(ctx, newReq("inviter-2", "b@VICTIM.example"))
+		assert.ErrorIs(t, err, ErrRateLimited)
+		_, err = svc.CreateInvitation(ctx, newReq("inviter-2", "b@other.example"))
+		assert.NoError(t, err)
+
+		results := svc.CreateInvitations(ctx, []CreateInvitationRequest{newReq("inviter-3", "c@third.example"), newReq("inviter-3", "d@third.example")})
+		require.NoError(t, results[0].Err)
+		assert.ErrorIs(t, results[1].Err, ErrRateLimited)
+	})
+}
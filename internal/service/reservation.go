@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Reservation menandai bahwa sebuah undangan sedang dipakai oleh proses pendaftaran.
+// Selama reservasi berlaku, token tidak dapat diterima oleh pemanggil lain; jika
+// pendaftaran gagal, reservasi dapat dilepas atau dibiarkan kedaluwarsa lalu dicoba lagi.
+type Reservation struct {
+	ID         string          `json:"reservation_id"`
+	ExpiresAt  time.Time       `json:"expires_at"`
+	Invitation *InvitationData `json:"invitation"`
+}
+
+// reservationKey menyimpan ID reservasi aktif untuk sebuah hash token.
+func reservationKey(tokenHash string) string {
+	return fmt.Sprintf("invitation_reservation:%s", tokenHash)
+}
+
+// ReserveInvitation memesan undangan untuk sementara tanpa mengonsumsi tokennya.
+func (s *invitationService) ReserveInvitation(ctx context.Context, token string) (*Reservation, error) {
+	tokenHash, err := s.lookupHash(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.readInvitation(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	reservationID := s.newID()
+	ok, err := s.redisClient.SetNX(ctx, reservationKey(tokenHash), reservationID, s.leaseDuration).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvitationReserved
+	}
+
+	return &Reservation{
+		ID:         reservationID,
+		ExpiresAt:  s.now().UTC().Add(s.leaseDuration),
+		Invitation: data,
+	}, nil
+}
+
+// CommitInvitation mengonsumsi undangan yang sebelumnya direservasi oleh pemanggil.
+func (s *invitationService) CommitInvitation(ctx context.Context, token, reservationID string) (*InvitationData, error) {
+	tokenHash, err := s.lookupHash(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return s.consumeToken(ctx, tokenHash, reservationID)
+}
+
+// releaseScript menghapus key hanya jika nilainya masih sama dengan ARGV[1]. Untuk reservasi,
+// ini memastikan reservasi baru yang dibuat setelah lease lama berakhir tidak ikut terhapus.
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// ReleaseInvitation melepas reservasi sehingga undangan dapat dipakai kembali,
+// misalnya setelah pembuatan akun gagal.
+func (s *invitationService) ReleaseInvitation(ctx context.Context, token, reservationID string) error {
+	tokenHash, err := s.lookupHash(ctx, token)
+	if err != nil {
+		return err
+	}
+	released, err := releaseScript.Run(ctx, s.redisClient, []string{reservationKey(tokenHash)}, reservationID).Int()
+	if err != nil {
+		return err
+	}
+	if released == 0 {
+		return ErrReservationMismatch
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/model"
+)
+
+// DefaultRoleCacheTTL dan DefaultRoleServiceTimeout dipakai jika HTTPRoleValidator tidak dikonfigurasi.
+const (
+	DefaultRoleCacheTTL       = 5 * time.Minute
+	DefaultRoleServiceTimeout = 5 * time.Second
+)
+
+// RoleValidator memeriksa role undangan sebelum undangan dibuat. ValidateRole mengembalikan
+// ErrUnknownRole jika role tidak ada di katalog tenant, atau ErrRoleNotGrantable jika role
+// lebih tinggi dari role pengundang.
+type RoleValidator interface {
+	ValidateRole(ctx context.Context, tenantID, role, inviterRole string) error
+}
+
+// WithRoleValidator mengaktifkan validasi role undangan terhadap katalog role tenant.
+func WithRoleValidator(validator RoleValidator) Option {
+	return func(s *invitationService) {
+		s.roleValidator = validator
+	}
+}
+
+// validateRole menjalankan RoleValidator jika dikonfigurasi.
+func (s *invitationService) validateRole(ctx context.Context, req CreateInvitationRequest) error {
+	if s.roleValidator == nil {
+		return nil
+	}
+	return s.roleValidator.ValidateRole(ctx, req.TenantID, req.Role, req.InviterRole)
+}
+
+// StaticRoleValidator memvalidasi role terhadap daftar role dari konfigurasi yang berlaku
+// untuk semua tenant. Urutan daftar menentukan tingkat hak akses, dari yang terendah.
+type StaticRoleValidator struct {
+	ranks map[string]int
+}
+
+// NewStaticRoleValidator membuat validator dari daftar role yang diurutkan dari hak akses
+// terendah ke tertinggi, misalnya ["viewer", "editor", "admin"].
+func NewStaticRoleValidator(roles []string) *StaticRoleValidator {
+	ranks := make(map[string]int, len(roles))
+	for i, role := range roles {
+		if role = strings.TrimSpace(role); role != "" {
+			ranks[role] = i
+		}
+	}
+	return &StaticRoleValidator{ranks: ranks}
+}
+
+// ValidateRole memastikan role dikenal dan tingkatnya tidak melebihi role pengundang.
+func (v *StaticRoleValidator) ValidateRole(_ context.Context, _, role, inviterRole string) error {
+	rank, ok := v.ranks[role]
+	if !ok {
+		return ErrUnknownRole
+	}
+	inviterRank, ok := v.ranks[inviterRole]
+	if !ok || rank > inviterRank {
+		return ErrRoleNotGrantable
+	}
+	return nil
+}
+
+// HTTPRoleValidator memvalidasi role terhadap katalog role tenant yang dikelola oleh
+// service role/permission. Sebuah role dianggap lebih tinggi dari role pengundang jika
+// memiliki izin yang tidak dimiliki role pengundang.
+type HTTPRoleValidator struct {
+	baseURL    string
+	httpClient *http.Client
+	cacheTTL   time.Duration
+	now        func() time.Time
+
+	mu       sync.RWMutex
+	catalogs map[string]cachedRoleCatalog
+}
+
+type cachedRoleCatalog struct {
+	roles     map[string]map[string]struct{} // nama role -> set izin
+	expiresAt time.Time
+}
+
+// NewHTTPRoleValidator membuat validator yang membaca katalog role dari GET {baseURL}/roles
+// dengan header X-Tenant-ID. Katalog disimpan per tenant selama cacheTTL.
+func NewHTTPRoleValidator(baseURL string, timeout, cacheTTL time.Duration) *HTTPRoleValidator {
+	if timeout <= 0 {
+		timeout = DefaultRoleServiceTimeout
+	}
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultRoleCacheTTL
+	}
+	return &HTTPRoleValidator{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+		cacheTTL:   cacheTTL,
+		now:        time.Now,
+		catalogs:   make(map[string]cachedRoleCatalog),
+	}
+}
+
+// ValidateRole memastikan role ada di katalog tenant dan izinnya merupakan bagian dari izin
+// role pengundang.
+func (v *HTTPRoleValidator) ValidateRole(ctx context.Context, tenantID, role, inviterRole string) error {
+	roles, err := v.catalog(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	granted, ok := roles[role]
+	if !ok {
+		return ErrUnknownRole
+	}
+	held, ok := roles[inviterRole]
+	if !ok {
+		return ErrRoleNotGrantable
+	}
+	for permission := range granted {
+		if _, ok := held[permission]; !ok {
+			return ErrRoleNotGrantable
+		}
+	}
+	return nil
+}
+
+// catalog mengambil katalog role tenant, menggunakan cache jika masih berlaku.
+func (v *HTTPRoleValidator) catalog(ctx context.Context, tenantID string) (map[string]map[string]struct{}, error) {
+	v.mu.RLock()
+	cached, found := v.catalogs[tenantID]
+	v.mu.RUnlock()
+	if found && v.now().Before(cached.expiresAt) {
+		return cached.roles, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.baseURL+"/roles", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Tenant-ID", tenantID)
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil katalog role tenant: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gagal mengambil katalog role tenant: status %d", resp.StatusCode)
+	}
+
+	var list []model.Role
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("gagal membaca katalog role tenant: %w", err)
+	}
+	roles := make(map[string]map[string]struct{}, len(list))
+	for _, role := range list {
+		permissions := make(map[string]struct{}, len(role.Permissions))
+		for _, permission := range role.Permissions {
+			permissions[permission] = struct{}{}
+		}
+		roles[role.Name] = permissions
+	}
+
+	v.mu.Lock()
+	v.catalogs[tenantID] = cachedRoleCatalog{roles: roles, expiresAt: v.now().Add(v.cacheTTL)}
+	v.mu.Unlock()
+	return roles, nil
+}
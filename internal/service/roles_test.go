@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleValidation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Static Catalog", func(t *testing.T) {
+		validator := NewStaticRoleValidator([]string{"viewer", " editor", "admin"})
+
+		assert.NoError(t, validator.ValidateRole(ctx, "tenant-1", "editor", "admin"))
+		assert.NoError(t, validator.ValidateRole(ctx, "tenant-1", "editor", "editor"))
+		assert.ErrorIs(t, validator.ValidateRole(ctx, "tenant-1", "admn", "admin"), ErrUnknownRole)
+		assert.ErrorIs(t, validator.ValidateRole(ctx, "tenant-1", "admin", "editor"), ErrRoleNotGrantable)
+		assert.ErrorIs(t, validator.ValidateRole(ctx, "tenant-1", "viewer", ""), ErrRoleNotGrantable)
+	})
+
+	t.Run("HTTP Catalog Compares Permissions", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			assert.Equal(t, "/roles", r.URL.Path)
+			assert.Equal(t, "tenant-1", r.Header.Get("X-Tenant-ID"))
+			_, _ = w.Write([]byte(`[
+				{"name": "viewer", "permissions": ["invoices:read"]},
+				{"name": "accountant", "permissions": ["invoices:read", "invoices:write"]},
+				{"name": "auditor", "permissions": ["invoices:read", "audit:read"]}
+			]`))
+		}))
+		defer server.Close()
+		validator := NewHTTPRoleValidator(server.URL+"/", time.Second, time.Minute)
+
+		assert.NoError(t, validator.ValidateRole(ctx, "tenant-1", "viewer", "accountant"))
+		assert.ErrorIs(t, validator.ValidateRole(ctx, "tenant-1", "auditor", "accountant"), ErrRoleNotGrantable)
+		assert.ErrorIs(t, validator.ValidateRole(ctx, "tenant-1", "admn", "accountant"), ErrUnknownRole)
+		assert.Equal(t, 1, requests, "katalog role harus diambil dari cache")
+	})
+
+	t.Run("HTTP Catalog Unavailable", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		validator := NewHTTPRoleValidator(server.URL, time.Second, time.Minute)
+
+		err := validator.ValidateRole(ctx, "tenant-1", "viewer", "admin")
+
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrUnknownRole)
+	})
+
+	t.Run("Service Rejects Before Writing", func(t *testing.T) {
+		mockPublisher := new(MockQueuePublisher)
+		mockPublisher.On("Enqueue", ctx, mock.Anything).Return(nil)
+		svc, mr := newMiniredisService(t, mockPublisher, &UUIDTokenGenerator{})
+		svc.roleValidator = NewStaticRoleValidator([]string{"viewer", "admin"})
+
+		_, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "user@example.com", Role: "admin", TenantID: "tenant-1", InviterID: "inviter-1", InviterRole: "viewer"})
+		assert.ErrorIs(t, err, ErrRoleNotGrantable)
+		assert.False(t, mr.Exists(tenantIndexKey("tenant-1")))
+
+		results := svc.CreateInvitations(ctx, []CreateInvitationRequest{
+			{Email: "a@example.com", Role: "admn", TenantID: "tenant-1", InviterID: "inviter-1", InviterRole: "admin"},
+			{Email: "b@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1", InviterRole: "admin"},
+		})
+		assert.ErrorIs(t, results[0].Err, ErrUnknownRole)
+		require.NoError(t, results[1].Err)
+		mockPublisher.AssertNumberOfCalls(t, "Enqueue", 1)
+	})
+}
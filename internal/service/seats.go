@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// UnlimitedSeats dikembalikan SeatProvider untuk tenant yang paketnya tidak membatasi jumlah pengguna.
+const UnlimitedSeats = -1
+
+// DefaultSeatServiceTimeout dipakai jika HTTPSeatProvider tidak dikonfigurasi.
+const DefaultSeatServiceTimeout = DefaultRoleServiceTimeout
+
+// SeatProvider memberikan sisa kursi paket tenant, yaitu jumlah pengguna yang masih dapat
+// ditambahkan. Undangan aktif yang belum diterima ikut memakai kursi, sehingga undangan baru
+// ditolak dengan ErrSeatLimitExceeded jika jumlahnya akan melebihi sisa kursi.
+type SeatProvider interface {
+	RemainingSeats(ctx context.Context, tenantID string) (int, error)
+}
+
+// WithSeatProvider mengaktifkan pemeriksaan kuota kursi saat undangan dibuat.
+func WithSeatProvider(provider SeatProvider) Option {
+	return func(s *invitationService) {
+		s.seatProvider = provider
+	}
+}
+
+// ConfigSeatProvider memberikan jumlah kursi tetap per tenant dari konfigurasi, tanpa
+// memperhitungkan pengguna yang sudah aktif.
+type ConfigSeatProvider struct {
+	defaultSeats int
+	seats        map[string]int
+}
+
+// NewConfigSeatProvider membuat provider dengan defaultSeats untuk semua tenant dan pengecualian
+// per tenant dalam format "tenant-a=50,tenant-b=10". Nilai 0 atau negatif berarti tanpa batas.
+func NewConfigSeatProvider(defaultSeats int, overrides string) (*ConfigSeatProvider, error) {
+	seats := make(map[string]int)
+	for _, entry := range strings.Split(overrides, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		tenantID, value, ok := strings.Cut(entry, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || err != nil {
+			return nil, fmt.Errorf("format kuota kursi tidak valid: %q", entry)
+		}
+		seats[strings.TrimSpace(tenantID)] = n
+	}
+	return &ConfigSeatProvider{defaultSeats: defaultSeats, seats: seats}, nil
+}
+
+func (p *ConfigSeatProvider) RemainingSeats(_ context.Context, tenantID string) (int, error) {
+	n, ok := p.seats[tenantID]
+	if !ok {
+		n = p.defaultSeats
+	}
+	if n <= 0 {
+		return UnlimitedSeats, nil
+	}
+	return n, nil
+}
+
+// HTTPSeatProvider membaca kuota kursi tenant dari service billing. Kuota tidak di-cache
+// karena jumlah pengguna aktif berubah setiap kali undangan diterima.
+type HTTPSeatProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// seatUsage adalah respons GET {baseURL}/tenants/{tenantID}/seats. Limit null berarti paket
+// tenant tidak membatasi jumlah pengguna.
+type seatUsage struct {
+	Limit *int `json:"limit"`
+	Used  int  `json:"used"`
+}
+
+// NewHTTPSeatProvider membuat provider yang memanggil GET {baseURL}/tenants/{tenantID}/seats.
+func NewHTTPSeatProvider(baseURL string, timeout time.Duration) *HTTPSeatProvider {
+	if timeout <= 0 {
+		timeout = DefaultSeatServiceTimeout
+	}
+	return &HTTPSeatProvider{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *HTTPSeatProvider) RemainingSeats(ctx context.Context, tenantID string) (int, error) {
+	endpoint := fmt.Sprintf("%s/tenants/%s/seats", p.baseURL, url.PathEscape(tenantID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("gagal mengambil kuota kursi tenant: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("gagal mengambil kuota kursi tenant: status %d", resp.StatusCode)
+	}
+
+	var usage seatUsage
+	if err := json.NewDecoder(resp.Body).Decode(&usage); err != nil {
+		return 0, fmt.Errorf("gagal membaca kuota kursi tenant: %w", err)
+	}
+	if usage.Limit == nil {
+		return UnlimitedSeats, nil
+	}
+	return max(*usage.Limit-usage.Used, 0), nil
+}
+
+// seatQuota melacak sisa kursi tenant selama satu transaksi pembuatan undangan. Undangan
+// aktif dihitung dari indeks undangan tenant; undangan kedaluwarsa yang belum dibersihkan
+// ExpirySweeper ikut terhitung sehingga kuota tidak pernah terlampaui.
+type seatQuota struct {
+	remaining   map[string]int   // sisa kursi tenant yang dibatasi
+	outstanding map[string]int64 // undangan aktif, termasuk yang direncanakan dalam transaksi
+}
+
+// loadSeatQuota mengambil sisa kursi setiap tenant sebelum transaksi dimulai, karena
+// SeatProvider dapat memanggil service lain dan transaksi dapat diulang.
+func (s *invitationService) loadSeatQuota(ctx context.Context, tenantIDs ...string) (*seatQuota, error) {
+	quota := &seatQuota{remaining: make(map[string]int)}
+	if s.seatProvider == nil {
+		return quota, nil
+	}
+	for _, tenantID := range tenantIDs {
+		if _, ok := quota.remaining[tenantID]; ok {
+			continue
+		}
+		seats, err := s.seatProvider.RemainingSeats(ctx, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		if seats != UnlimitedSeats {
+			quota.remaining[tenantID] = seats
+		}
+	}
+	return quota, nil
+}
+
+// watchKeys mengembalikan indeks tenant yang dibatasi agar transaksi diulang jika undangan
+// lain dibuat atau diterima secara bersamaan.
+func (q *seatQuota) watchKeys() []string {
+	keys := make([]string, 0, len(q.remaining))
+	for tenantID := range q.remaining {
+		keys = append(keys, tenantIndexKey(tenantID))
+	}
+	return keys
+}
+
+// reset membuang hitungan dari percobaan transaksi sebelumnya.
+func (q *seatQuota) reset() {
+	q.outstanding = make(map[string]int64, len(q.remaining))
+}
+
+// take memakai satu kursi tenant untuk undangan baru, atau mengembalikan ErrSeatLimitExceeded
+// jika kursi sudah habis.
+func (q *seatQuota) take(ctx context.Context, tx *redis.Tx, tenantID string) error {
+	remaining, limited := q.remaining[tenantID]
+	if !limited {
+		return nil
+	}
+	outstanding, counted := q.outstanding[tenantID]
+	if !counted {
+		n, err := tx.ZCard(ctx, tenantIndexKey(tenantID)).Result()
+		if err != nil {
+			return err
+		}
+		outstanding = n
+	}
+	if outstanding >= int64(remaining) {
+		q.outstanding[tenantID] = outstanding
+		return ErrSeatLimitExceeded
+	}
+	q.outstanding[tenantID] = outstanding + 1
+	return nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvitationService_SeatLimits(t *testing.T) {
+	ctx := context.Background()
+	newReq := func(email string) CreateInvitationRequest {
+		return CreateInvitationRequest{Email: email, Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"}
+	}
+
+	t.Run("Outstanding Invitations Use Seats", func(t *testing.T) {
+		svc, _ := newDeliveringService(t, &UUIDTokenGenerator{})
+		provider, err := NewConfigSeatProvider(0, "tenant-1=2, tenant-2=1")
+		require.NoError(t, err)
+		svc.seatProvider = provider
+
+		first, err := svc.CreateInvitation(ctx, newReq("a@example.com"))
+		require.NoError(t, err)
+		_, err = svc.CreateInvitation(ctx, newReq("b@example.com"))
+		require.NoError(t, err)
+		_, err = svc.CreateInvitation(ctx, newReq("c@example.com"))
+		assert.ErrorIs(t, err, ErrSeatLimitExceeded)
+
+		upsert := newReq("a@example.com")
+		upsert.Upsert = true
+		_, err = svc.CreateInvitation(ctx, upsert)
+		require.NoError(t, err, "upsert memakai kursi undangan yang diganti")
+
+		_, err = svc.RevokeInvitation(ctx, "tenant-1", first.ID, "admin-1")
+		require.NoError(t, err)
+		_, err = svc.CreateInvitation(ctx, newReq("c@example.com"))
+		assert.NoError(t, err, "undangan yang dicabut mengembalikan kursinya")
+
+		_, err = svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "a@example.com", Role: "viewer", TenantID: "tenant-3", InviterID: "inviter-1"})
+		assert.NoError(t, err, "tenant tanpa batas tidak diperiksa")
+	})
+
+	t.Run("Bulk Stops At Remaining Seats", func(t *testing.T) {
+		svc, _ := newDeliveringService(t, &UUIDTokenGenerator{})
+		svc.seatProvider, _ = NewConfigSeatProvider(2, "")
+
+		results := svc.CreateInvitations(ctx, []CreateInvitationRequest{newReq("a@example.com"), newReq("b@example.com"), newReq("c@example.com")})
+
+		require.NoError(t, results[0].Err)
+		require.NoError(t, results[1].Err)
+		assert.ErrorIs(t, results[2].Err, ErrSeatLimitExceeded)
+	})
+
+	t.Run("HTTP Provider", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/tenants/tenant-1/seats":
+				_, _ = w.Write([]byte(`{"limit": 10, "used": 9}`))
+			case "/tenants/tenant-2/seats":
+				_, _ = w.Write([]byte(`{"limit": null, "used": 40}`))
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+		defer server.Close()
+		provider := NewHTTPSeatProvider(server.URL+"/", time.Second)
+
+		seats, err := provider.RemainingSeats(ctx, "tenant-1")
+		require.NoError(t, err)
+		assert.Equal(t, 1, seats)
+		seats, err = provider.RemainingSeats(ctx, "tenant-2")
+		require.NoError(t, err)
+		assert.Equal(t, UnlimitedSeats, seats)
+
+		svc, mr := newDeliveringService(t, &UUIDTokenGenerator{})
+		svc.seatProvider = provider
+		_, err = svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "a@example.com", Role: "viewer", TenantID: "tenant-9", InviterID: "inviter-1"})
+		require.Error(t, err, "kuota yang tidak dapat dibaca menolak undangan")
+		assert.False(t, mr.Exists(tenantIndexKey("tenant-9")))
+	})
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultShortCodeLength adalah panjang kode undangan pendek termasuk karakter checksum,
+	// misalnya "K7QF-9M2X": 7 karakter acak (35 bit) dan 1 karakter checksum.
+	DefaultShortCodeLength = 8
+	// MinShortCodeLength dan MaxShortCodeLength membatasi panjang kode yang dapat dikonfigurasi.
+	MinShortCodeLength = 6
+	MaxShortCodeLength = 16
+
+	// shortCodeGroup adalah jumlah karakter per kelompok yang dipisah tanda hubung.
+	shortCodeGroup = 4
+
+	// maxShortCodeAttempts membatasi pembuatan ulang kode yang bertabrakan dengan undangan aktif.
+	maxShortCodeAttempts = 5
+)
+
+// crockfordAlphabet adalah alfabet Crockford base32 tanpa I, L, O dan U.
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// crockfordAliases memetakan karakter yang mudah tertukar ke karakter Crockford yang dimaksud.
+var crockfordAliases = strings.NewReplacer("O", "0", "I", "1", "L", "1")
+
+// ErrShortCodesDisabled dikembalikan ketika undangan meminta kode pendek tetapi
+// WithShortCodes tidak dikonfigurasi.
+var ErrShortCodesDisabled = errors.New("kode undangan pendek tidak diaktifkan")
+
+// ShortCodeGenerator membuat kode undangan pendek yang dapat diketik manusia, untuk penerima
+// yang menerima undangan di kertas atau SMS dan tidak dapat membuka tautan. Karakter terakhir
+// adalah checksum Luhn mod 32 sehingga salah ketik satu karakter dan hampir semua pertukaran
+// dua karakter bersebelahan (kecuali 0 dan Z) ditolak tanpa mengakses Redis. Entropinya jauh
+// lebih kecil dari token biasa, sehingga endpoint token perlu memakai TokenGuard khusus kode
+// dari NewShortCodeGuard.
+type ShortCodeGenerator struct {
+	length int
+	random io.Reader
+}
+
+// NewShortCodeGenerator membuat generator kode dengan panjang length termasuk checksum.
+func NewShortCodeGenerator(length int) (*ShortCodeGenerator, error) {
+	if length <= 0 {
+		length = DefaultShortCodeLength
+	}
+	if length < MinShortCodeLength || length > MaxShortCodeLength {
+		return nil, fmt.Errorf("panjang kode undangan harus antara %d dan %d karakter", MinShortCodeLength, MaxShortCodeLength)
+	}
+	return &ShortCodeGenerator{length: length, random: rand.Reader}, nil
+}
+
+// WithShortCodes mengizinkan undangan dengan CreateInvitationRequest.ShortCode memakai kode
+// pendek dari generator, di samping token biasa untuk undangan lainnya.
+func WithShortCodes(generator *ShortCodeGenerator) Option {
+	return func(s *invitationService) {
+		s.shortCodes = generator
+	}
+}
+
+// Generate membuat kode acak berkelompok empat karakter, misalnya "K7QF-9M2X". Klaim undangan
+// tidak disematkan.
+func (g *ShortCodeGenerator) Generate(TokenClaims) (string, error) {
+	code := make([]byte, g.length-1, g.length)
+	alphabetSize := big.NewInt(int64(len(crockfordAlphabet)))
+	for i := range code {
+		n, err := rand.Int(g.random, alphabetSize)
+		if err != nil {
+			return "", fmt.Errorf("gagal membuat kode undangan: %w", err)
+		}
+		code[i] = crockfordAlphabet[n.Int64()]
+	}
+	code = append(code, crockfordAlphabet[luhnCheckValue(string(code))])
+
+	var formatted strings.Builder
+	for i, c := range code {
+		if i > 0 && i%shortCodeGroup == 0 {
+			formatted.WriteByte('-')
+		}
+		formatted.WriteByte(c)
+	}
+	return formatted.String(), nil
+}
+
+// Normalize mengubah kode masukan ke bentuk kanonis: huruf besar, tanpa tanda hubung atau
+// spasi, dengan O dibaca 0 serta I dan L dibaca 1. ErrInvalidToken dikembalikan jika panjang,
+// alfabet atau checksum tidak cocok.
+func (g *ShortCodeGenerator) Normalize(code string) (string, error) {
+	canonical := canonicalShortCode(code)
+	if len(canonical) != g.length || strings.Trim(canonical, crockfordAlphabet) != "" {
+		return "", ErrInvalidToken
+	}
+	if luhnCheckValue(canonical[:len(canonical)-1]) != strings.IndexByte(crockfordAlphabet, canonical[len(canonical)-1]) {
+		return "", ErrInvalidToken
+	}
+	return canonical, nil
+}
+
+// IsShortCode melaporkan apakah token berbentuk kode undangan pendek, tanpa memeriksa
+// checksum, sehingga handler dapat memilih TokenGuard yang lebih ketat untuk kode yang salah
+// ketik sekalipun.
+func IsShortCode(token string) bool {
+	canonical := canonicalShortCode(token)
+	return len(canonical) >= MinShortCodeLength && len(canonical) <= MaxShortCodeLength &&
+		strings.Trim(canonical, crockfordAlphabet) == ""
+}
+
+func canonicalShortCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	return crockfordAliases.Replace(code)
+}
+
+// luhnCheckValue menghitung nilai checksum Luhn mod 32 untuk kode tanpa karakter checksum.
+func luhnCheckValue(code string) int {
+	const n = len(crockfordAlphabet)
+	factor, sum := 2, 0
+	for i := len(code) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(crockfordAlphabet, code[i])
+		sum += addend/n + addend%n
+		factor = 3 - factor
+	}
+	return (n - sum%n) % n
+}
+
+// newToken membuat token undangan beserta hash penyimpanannya. Undangan dengan kode pendek
+// memakai ShortCodeGenerator; karena ruang kodenya kecil, kode yang sudah dipakai undangan
+// aktif dibuat ulang, dan key-nya di-watch agar kode yang sama tidak ditulis bersamaan.
+// ErrShortCodesDisabled dikembalikan jika undangan berkode pendek dikirim ulang setelah
+// WithShortCodes dinonaktifkan.
+func (s *invitationService) newToken(ctx context.Context, tx *redis.Tx, data *InvitationData) (token, tokenHash string, err error) {
+	claims := TokenClaims{InvitationID: data.ID, TenantID: data.TenantID, ExpiresAt: data.ExpiresAt}
+	if data.ShortCode && s.shortCodes == nil {
+		return "", "", ErrShortCodesDisabled
+	}
+	if !data.ShortCode {
+		if token, err = s.tokenGenerator.Generate(claims); err != nil {
+			return "", "", err
+		}
+		tokenHash, err = s.storedHash(token)
+		return token, tokenHash, err
+	}
+
+	for attempt := 0; attempt < maxShortCodeAttempts; attempt++ {
+		if token, err = s.shortCodes.Generate(claims); err != nil {
+			return "", "", err
+		}
+		canonical, err := s.shortCodes.Normalize(token)
+		if err != nil {
+			return "", "", err
+		}
+		hashes, err := s.candidateHashes(canonical)
+		if err != nil {
+			return "", "", err
+		}
+		keys := make([]string, len(hashes))
+		for i, hash := range hashes {
+			keys[i] = tokenKey(hash)
+		}
+		if err := tx.Watch(ctx, keys...).Err(); err != nil {
+			return "", "", err
+		}
+		existing, err := tx.Exists(ctx, keys...).Result()
+		if err != nil {
+			return "", "", err
+		}
+		if existing == 0 {
+			return token, hashes[0], nil
+		}
+	}
+	return "", "", errors.New("gagal membuat kode undangan yang belum dipakai")
+}
+
+// canonicalToken menyiapkan token masukan untuk di-hash. Kode pendek dinormalisasi dan
+// checksum-nya diperiksa; token lain diperiksa dengan TokenVerifier jika generator mendukungnya.
+// Klaim token bertanda tangan dikembalikan agar dapat dicocokkan dengan undangan yang tersimpan,
+// dan nil untuk token tanpa klaim.
+func (s *invitationService) canonicalToken(token string) (string, *TokenClaims, error) {
+	if s.shortCodes != nil && IsShortCode(token) {
+		code, err := s.shortCodes.Normalize(token)
+		return code, nil, err
+	}
+	if verifier, ok := s.tokenGenerator.(TokenVerifier); ok {
+		claims, err := verifier.Verify(token)
+		if err != nil {
+			return "", nil, err
+		}
+		return token, claims, nil
+	}
+	return token, nil, nil
+}
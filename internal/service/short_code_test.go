@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShortCodeGenerator(t *testing.T) {
+	g, err := NewShortCodeGenerator(DefaultShortCodeLength)
+	require.NoError(t, err)
+
+	t.Run("Format And Normalization", func(t *testing.T) {
+		code, err := g.Generate(TokenClaims{})
+		require.NoError(t, err)
+		assert.Regexp(t, `^[0-9A-HJKMNP-TV-Z]{4}-[0-9A-HJKMNP-TV-Z]{4}$`, code)
+		assert.True(t, IsShortCode(code))
+
+		canonical, err := g.Normalize(code)
+		require.NoError(t, err)
+		assert.Equal(t, strings.ReplaceAll(code, "-", ""), canonical)
+		typed := strings.NewReplacer("0", "o", "1", "l").Replace(strings.ToLower(canonical[:4]) + " " + canonical[4:])
+		normalized, err := g.Normalize(typed)
+		require.NoError(t, err)
+		assert.Equal(t, canonical, normalized)
+	})
+
+	t.Run("Checksum Rejects Typos", func(t *testing.T) {
+		canonical := "K7QF9M2" + string(crockfordAlphabet[luhnCheckValue("K7QF9M2")])
+		_, err := g.Normalize(canonical)
+		require.NoError(t, err)
+
+		for i := range canonical {
+			for _, c := range crockfordAlphabet {
+				if byte(c) == canonical[i] {
+					continue
+				}
+				typo := canonical[:i] + string(c) + canonical[i+1:]
+				_, err := g.Normalize(typo)
+				assert.ErrorIs(t, err, ErrInvalidToken, typo)
+			}
+		}
+		_, err = g.Normalize("7KQF9M2" + canonical[7:])
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		_, err = g.Normalize(canonical + "0")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Invalid Length", func(t *testing.T) {
+		_, err := NewShortCodeGenerator(MinShortCodeLength - 1)
+		assert.Error(t, err)
+		assert.False(t, IsShortCode("3f1c2d4e-0000-4000-8000-000000000000"))
+	})
+}
+
+func TestInvitationService_ShortCodes(t *testing.T) {
+	ctx := context.Background()
+	newShortCodeService := func(t *testing.T) (*invitationService, *MockQueuePublisher) {
+		mockPublisher := new(MockQueuePublisher)
+		svc, _ := newMiniredisService(t, mockPublisher, &UUIDTokenGenerator{})
+		shortCodes, err := NewShortCodeGenerator(DefaultShortCodeLength)
+		require.NoError(t, err)
+		WithShortCodes(shortCodes)(svc)
+		return svc, mockPublisher
+	}
+	request := CreateInvitationRequest{Email: "picker@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1", ShortCode: true}
+
+	t.Run("Code Is Sent And Accepted As Typed", func(t *testing.T) {
+		svc, mockPublisher := newShortCodeService(t)
+		var sentCode string
+		mockPublisher.On("Enqueue", ctx, mock.Anything).Run(func(args mock.Arguments) {
+			sentCode = args.Get(1).(client.NotificationPayload).TemplateData["InvitationCode"].(string)
+		}).Return(nil).Once()
+
+		invitation, err := svc.CreateInvitation(ctx, request)
+		require.NoError(t, err)
+		assert.True(t, invitation.ShortCode)
+
+		data, err := svc.PreviewInvitation(ctx, strings.ToLower(strings.ReplaceAll(sentCode, "-", " ")))
+		require.NoError(t, err)
+		assert.Equal(t, invitation.ID, data.ID)
+		data, err = svc.ValidateInvitation(ctx, sentCode)
+		require.NoError(t, err)
+		assert.Equal(t, invitation.ID, data.ID)
+	})
+
+	t.Run("Regenerates Codes In Use", func(t *testing.T) {
+		svc, mockPublisher := newShortCodeService(t)
+		mockPublisher.On("Enqueue", ctx, mock.Anything).Return(nil)
+		// Sumber acak konstan selalu menghasilkan kode yang sama.
+		svc.shortCodes.random = strings.NewReader(strings.Repeat("\x01", 1000))
+
+		_, err := svc.CreateInvitation(ctx, request)
+		require.NoError(t, err)
+		other := request
+		other.Email = "packer@example.com"
+		_, err = svc.CreateInvitation(ctx, other)
+		assert.ErrorContains(t, err, "belum dipakai")
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		svc, _ := newMiniredisService(t, new(MockQueuePublisher), &UUIDTokenGenerator{})
+		_, err := svc.CreateInvitation(ctx, request)
+		assert.ErrorIs(t, err, ErrShortCodesDisabled)
+	})
+
+	t.Run("Resend After Disabled", func(t *testing.T) {
+		svc, mockPublisher := newShortCodeService(t)
+		mockPublisher.On("Enqueue", ctx, mock.Anything).Return(nil).Once()
+		svc.resendCooldown = 0
+		invitation, err := svc.CreateInvitation(ctx, request)
+		require.NoError(t, err)
+
+		svc.shortCodes = nil
+		_, err = svc.ResendInvitation(ctx, "tenant-1", invitation.ID, "admin-1")
+		assert.ErrorIs(t, err, ErrShortCodesDisabled)
+		mockPublisher.AssertNumberOfCalls(t, "Enqueue", 1)
+	})
+}
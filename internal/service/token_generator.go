@@ -1,17 +1,34 @@
 package service
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenClaims adalah informasi undangan yang dapat disematkan ke dalam token.
+type TokenClaims struct {
+	InvitationID string
+	TenantID     string
+	ExpiresAt    time.Time
+}
 
 // TokenGenerator adalah interface untuk membuat token acak.
 // Dengan adanya interface, kita bisa menggantinya dengan mock saat testing.
 type TokenGenerator interface {
-	Generate() string
+	Generate(claims TokenClaims) (string, error)
+}
+
+// TokenVerifier diimplementasikan TokenGenerator yang tokennya dapat diperiksa tanpa Redis.
+// Verify mengembalikan ErrInvalidToken jika token dipalsukan atau sudah kedaluwarsa.
+type TokenVerifier interface {
+	Verify(token string) (*TokenClaims, error)
 }
 
 // UUIDTokenGenerator adalah implementasi nyata yang akan digunakan di produksi.
 type UUIDTokenGenerator struct{}
 
-// Generate membuat token acak menggunakan UUID v4.
-func (g *UUIDTokenGenerator) Generate() string {
-	return uuid.NewString()
+// Generate membuat token acak menggunakan UUID v4. Klaim undangan tidak disematkan.
+func (g *UUIDTokenGenerator) Generate(TokenClaims) (string, error) {
+	return uuid.NewString(), nil
 }
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// Cakupan penguncian percobaan token, juga dipakai sebagai label metrik.
+const (
+	guardScopeIP     = "ip"
+	guardScopeGlobal = "global"
+
+	// guardLevelRetention adalah lama tingkat penguncian sebuah IP diingat. Selama periode ini
+	// setiap penguncian berikutnya berlangsung dua kali lebih lama.
+	guardLevelRetention = 24 * time.Hour
+)
+
+// GuardLimits mengatur ambang kegagalan dan lama penguncian TokenGuard.
+type GuardLimits struct {
+	// Window dan MaxFailures: sebuah IP dikunci setelah MaxFailures token tidak valid dalam Window.
+	Window      time.Duration
+	MaxFailures int
+	// BaseLockout adalah lama penguncian pertama; setiap penguncian berikutnya berlipat dua
+	// hingga MaxLockout.
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	// Semua endpoint token dikunci selama GlobalLockout setelah GlobalMaxFailures token tidak
+	// valid dalam GlobalWindow dari seluruh IP, misalnya saat serangan terdistribusi.
+	GlobalWindow      time.Duration
+	GlobalMaxFailures int
+	GlobalLockout     time.Duration
+}
+
+// DefaultGuardLimits dipakai untuk nilai GuardLimits yang tidak diisi.
+var DefaultGuardLimits = GuardLimits{
+	Window:            15 * time.Minute,
+	MaxFailures:       10,
+	BaseLockout:       time.Minute,
+	MaxLockout:        time.Hour,
+	GlobalWindow:      time.Minute,
+	GlobalMaxFailures: 1000,
+	GlobalLockout:     time.Minute,
+}
+
+// DefaultShortCodeGuardLimits dipakai NewShortCodeGuard untuk nilai GuardLimits yang tidak
+// diisi. Kode pendek hanya memiliki sekitar 35 bit entropi, sehingga ambangnya jauh lebih
+// rendah dan penguncian lebih lama dibanding token biasa.
+var DefaultShortCodeGuardLimits = GuardLimits{
+	Window:            time.Hour,
+	MaxFailures:       5,
+	BaseLockout:       15 * time.Minute,
+	MaxLockout:        24 * time.Hour,
+	GlobalWindow:      time.Minute,
+	GlobalMaxFailures: 100,
+	GlobalLockout:     5 * time.Minute,
+}
+
+// TokenGuard membatasi tebakan token pada endpoint publik yang menerima token undangan.
+type TokenGuard interface {
+	// Check mengembalikan RetryAfterError berisi ErrTooManyAttempts jika IP atau seluruh
+	// endpoint sedang dikunci.
+	Check(ctx context.Context, clientIP string) error
+	// RecordFailure mencatat satu token tidak valid dari clientIP dan mengunci IP atau seluruh
+	// endpoint jika ambang kegagalan terlampaui.
+	RecordFailure(ctx context.Context, clientIP string) error
+}
+
+type redisTokenGuard struct {
+	redisClient *redis.Client
+	limits      GuardLimits
+	// prefix memisahkan penghitung dan penguncian token biasa dari kode pendek.
+	prefix string
+}
+
+// NewTokenGuard membuat TokenGuard yang menyimpan penghitung kegagalan dan penguncian di Redis,
+// sehingga berlaku di semua replika.
+func NewTokenGuard(redisClient *redis.Client, limits GuardLimits) TokenGuard {
+	return newRedisTokenGuard(redisClient, "invitation_guard", limits, DefaultGuardLimits)
+}
+
+// NewShortCodeGuard membuat TokenGuard untuk kode undangan pendek dengan penghitung dan
+// penguncian terpisah dari NewTokenGuard, sehingga batas ketatnya tidak mengunci pengguna
+// tautan undangan biasa.
+func NewShortCodeGuard(redisClient *redis.Client, limits GuardLimits) TokenGuard {
+	return newRedisTokenGuard(redisClient, "invitation_code_guard", limits, DefaultShortCodeGuardLimits)
+}
+
+func newRedisTokenGuard(redisClient *redis.Client, prefix string, limits, defaults GuardLimits) *redisTokenGuard {
+	defaultDuration := func(v *time.Duration, d time.Duration) {
+		if *v <= 0 {
+			*v = d
+		}
+	}
+	defaultInt := func(v *int, d int) {
+		if *v <= 0 {
+			*v = d
+		}
+	}
+	defaultDuration(&limits.Window, defaults.Window)
+	defaultInt(&limits.MaxFailures, defaults.MaxFailures)
+	defaultDuration(&limits.BaseLockout, defaults.BaseLockout)
+	defaultDuration(&limits.MaxLockout, defaults.MaxLockout)
+	defaultDuration(&limits.GlobalWindow, defaults.GlobalWindow)
+	defaultInt(&limits.GlobalMaxFailures, defaults.GlobalMaxFailures)
+	defaultDuration(&limits.GlobalLockout, defaults.GlobalLockout)
+	return &redisTokenGuard{redisClient: redisClient, limits: limits, prefix: prefix}
+}
+
+func (g *redisTokenGuard) key(kind, id string) string {
+	return fmt.Sprintf("%s:%s:%s", g.prefix, kind, id)
+}
+
+// recordFailureScript menambah penghitung kegagalan per IP dan global, lalu memasang penguncian
+// jika ambangnya tercapai. Penghitung dikosongkan setelah penguncian sehingga IP yang sama
+// harus kembali mencapai ambang sebelum dikunci lebih lama.
+//
+// KEYS[1] = kegagalan IP, KEYS[2] = tingkat penguncian IP, KEYS[3] = penguncian IP,
+// KEYS[4] = kegagalan global, KEYS[5] = penguncian global.
+// ARGV (milidetik kecuali ambang) = jendela IP, ambang IP, penguncian dasar, penguncian maksimum,
+// retensi tingkat, jendela global, ambang global, lama penguncian global.
+// Mengembalikan {lama penguncian IP, lama penguncian global}, 0 jika tidak ada penguncian baru.
+var recordFailureScript = redis.NewScript(`
+local locked, globalLocked = 0, 0
+local failures = redis.call('INCR', KEYS[1])
+if failures == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+if failures >= tonumber(ARGV[2]) then
+	local level = redis.call('INCR', KEYS[2])
+	redis.call('PEXPIRE', KEYS[2], ARGV[5])
+	locked = math.floor(math.min(tonumber(ARGV[3]) * 2 ^ (level - 1), tonumber(ARGV[4])))
+	redis.call('SET', KEYS[3], level, 'PX', locked)
+	redis.call('DEL', KEYS[1])
+end
+local global = redis.call('INCR', KEYS[4])
+if global == 1 then
+	redis.call('PEXPIRE', KEYS[4], ARGV[6])
+end
+if global >= tonumber(ARGV[7]) then
+	globalLocked = tonumber(ARGV[8])
+	redis.call('SET', KEYS[5], 1, 'PX', globalLocked)
+	redis.call('DEL', KEYS[4])
+end
+return {locked, globalLocked}
+`)
+
+func (g *redisTokenGuard) Check(ctx context.Context, clientIP string) error {
+	pipe := g.redisClient.Pipeline()
+	ipTTL := pipe.PTTL(ctx, g.key("lock", clientIP))
+	globalTTL := pipe.PTTL(ctx, g.key("lock", guardScopeGlobal))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("gagal memeriksa penguncian token: %w", err)
+	}
+	// PTTL bernilai negatif jika key tidak ada.
+	wait := max(ipTTL.Val(), globalTTL.Val())
+	if wait <= 0 {
+		return nil
+	}
+	return &RetryAfterError{Err: ErrTooManyAttempts, RetryAfter: wait}
+}
+
+func (g *redisTokenGuard) RecordFailure(ctx context.Context, clientIP string) error {
+	invalidTokenAttempts.Inc()
+	keys := []string{
+		g.key("failures", clientIP),
+		g.key("level", clientIP),
+		g.key("lock", clientIP),
+		g.key("failures", guardScopeGlobal),
+		g.key("lock", guardScopeGlobal),
+	}
+	l := g.limits
+	result, err := recordFailureScript.Run(ctx, g.redisClient, keys,
+		l.Window.Milliseconds(), l.MaxFailures, l.BaseLockout.Milliseconds(), l.MaxLockout.Milliseconds(),
+		guardLevelRetention.Milliseconds(), l.GlobalWindow.Milliseconds(), l.GlobalMaxFailures, l.GlobalLockout.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return fmt.Errorf("gagal mencatat percobaan token tidak valid: %w", err)
+	}
+
+	if result[0] > 0 {
+		tokenLockouts.WithLabelValues(guardScopeIP).Inc()
+		log.Warn().Str("client_ip", clientIP).Dur("lockout", time.Duration(result[0])*time.Millisecond).Msg("IP dikunci karena terlalu banyak token undangan tidak valid")
+	}
+	if result[1] > 0 {
+		tokenLockouts.WithLabelValues(guardScopeGlobal).Inc()
+		log.Error().Dur("lockout", time.Duration(result[1])*time.Millisecond).Msg("Endpoint token undangan dikunci karena lonjakan token tidak valid")
+	}
+	return nil
+}
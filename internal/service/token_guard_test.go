@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenGuard(t *testing.T) {
+	ctx := context.Background()
+	newGuard := func(t *testing.T, limits GuardLimits) (TokenGuard, *miniredis.Miniredis) {
+		mr := miniredis.RunT(t)
+		return NewTokenGuard(redis.NewClient(&redis.Options{Addr: mr.Addr()}), limits), mr
+	}
+	fail := func(t *testing.T, guard TokenGuard, ip string, times int) {
+		for i := 0; i < times; i++ {
+			require.NoError(t, guard.RecordFailure(ctx, ip))
+		}
+	}
+	retryAfter := func(t *testing.T, err error) time.Duration {
+		require.ErrorIs(t, err, ErrTooManyAttempts)
+		var retryErr *RetryAfterError
+		require.ErrorAs(t, err, &retryErr)
+		return retryErr.RetryAfter
+	}
+
+	t.Run("Progressive Lockout Per IP", func(t *testing.T) {
+		guard, mr := newGuard(t, GuardLimits{Window: time.Minute, MaxFailures: 3, BaseLockout: time.Minute, MaxLockout: 3 * time.Minute})
+		attemptsBefore := testutil.ToFloat64(invalidTokenAttempts)
+		lockoutsBefore := testutil.ToFloat64(tokenLockouts.WithLabelValues(guardScopeIP))
+
+		fail(t, guard, "10.0.0.1", 2)
+		require.NoError(t, guard.Check(ctx, "10.0.0.1"))
+		fail(t, guard, "10.0.0.1", 1)
+		assert.Equal(t, time.Minute, retryAfter(t, guard.Check(ctx, "10.0.0.1")))
+		assert.NoError(t, guard.Check(ctx, "10.0.0.2"), "IP lain tidak ikut dikunci")
+		assert.Equal(t, attemptsBefore+3, testutil.ToFloat64(invalidTokenAttempts))
+		assert.Equal(t, lockoutsBefore+1, testutil.ToFloat64(tokenLockouts.WithLabelValues(guardScopeIP)))
+
+		// Setiap penguncian berikutnya dua kali lebih lama hingga batas maksimum.
+		mr.FastForward(time.Minute)
+		require.NoError(t, guard.Check(ctx, "10.0.0.1"))
+		fail(t, guard, "10.0.0.1", 3)
+		assert.Equal(t, 2*time.Minute, retryAfter(t, guard.Check(ctx, "10.0.0.1")))
+		mr.FastForward(2 * time.Minute)
+		fail(t, guard, "10.0.0.1", 3)
+		assert.Equal(t, 3*time.Minute, retryAfter(t, guard.Check(ctx, "10.0.0.1")))
+	})
+
+	t.Run("Failures Outside Window Are Forgotten", func(t *testing.T) {
+		guard, mr := newGuard(t, GuardLimits{Window: time.Minute, MaxFailures: 3})
+
+		fail(t, guard, "10.0.0.1", 2)
+		mr.FastForward(time.Minute)
+		fail(t, guard, "10.0.0.1", 2)
+		assert.NoError(t, guard.Check(ctx, "10.0.0.1"))
+	})
+
+	t.Run("Global Lockout", func(t *testing.T) {
+		guard, mr := newGuard(t, GuardLimits{MaxFailures: 100, GlobalWindow: time.Minute, GlobalMaxFailures: 5, GlobalLockout: 30 * time.Second})
+		lockoutsBefore := testutil.ToFloat64(tokenLockouts.WithLabelValues(guardScopeGlobal))
+
+		for i := 0; i < 5; i++ {
+			fail(t, guard, fmt.Sprintf("10.0.1.%d", i), 1)
+		}
+		assert.Equal(t, 30*time.Second, retryAfter(t, guard.Check(ctx, "10.0.2.1")), "lonjakan terdistribusi mengunci semua IP")
+		assert.Equal(t, lockoutsBefore+1, testutil.ToFloat64(tokenLockouts.WithLabelValues(guardScopeGlobal)))
+
+		mr.FastForward(30 * time.Second)
+		assert.NoError(t, guard.Check(ctx, "10.0.2.1"))
+	})
+	t.Run("Short Code Guard Is Stricter And Separate", func(t *testing.T) {
+		guard, mr := newGuard(t, GuardLimits{})
+		codeGuard := NewShortCodeGuard(redis.NewClient(&redis.Options{Addr: mr.Addr()}), GuardLimits{})
+
+		fail(t, codeGuard, "10.0.0.1", DefaultShortCodeGuardLimits.MaxFailures)
+		assert.Equal(t, DefaultShortCodeGuardLimits.BaseLockout, retryAfter(t, codeGuard.Check(ctx, "10.0.0.1")))
+		assert.NoError(t, guard.Check(ctx, "10.0.0.1"), "penguncian kode tidak memblokir tautan undangan")
+	})
+}
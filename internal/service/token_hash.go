@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// WithTokenPepper mengaktifkan hash token HMAC-SHA256 dengan pepper dari keyring, sehingga
+// akses baca ke Redis saja tidak cukup untuk mencocokkan token dengan key undangan. Token baru
+// memakai pepper aktif; pepper lain di keyring tetap dicoba agar pepper dapat dirotasi. Dengan
+// acceptLegacy, undangan yang disimpan sebagai sha256 tanpa pepper sebelum fitur ini diaktifkan
+// tetap dapat dipakai hingga kedaluwarsa.
+func WithTokenPepper(peppers Keyring, acceptLegacy bool) Option {
+	return func(s *invitationService) {
+		s.peppers = peppers
+		s.acceptLegacyHash = acceptLegacy
+	}
+}
+
+// pepperedHash menghasilkan HMAC-SHA256 token dengan pepper kid. ID pepper disertakan di depan
+// hash agar key undangan menunjukkan pepper yang dipakai selama rotasi.
+func pepperedHash(kid string, pepper []byte, token string) string {
+	mac := hmac.New(sha256.New, pepper)
+	mac.Write([]byte(token))
+	return kid + ":" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// storedHash menghasilkan hash untuk menyimpan token baru: HMAC dengan pepper aktif, atau
+// sha256 jika pepper tidak dikonfigurasi.
+func (s *invitationService) storedHash(token string) (string, error) {
+	if s.peppers == nil {
+		return hashToken(token), nil
+	}
+	kid, pepper, err := s.peppers.ActiveKey()
+	if err != nil {
+		return "", fmt.Errorf("gagal mengambil pepper token: %w", err)
+	}
+	return pepperedHash(kid, pepper, token), nil
+}
+
+// candidateHashes mengembalikan semua hash yang mungkin dipakai saat token disimpan, dimulai
+// dari pepper aktif dan diakhiri hash lama tanpa pepper jika masih diterima.
+func (s *invitationService) candidateHashes(token string) ([]string, error) {
+	if s.peppers == nil {
+		return []string{hashToken(token)}, nil
+	}
+	activeKid, activePepper, err := s.peppers.ActiveKey()
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil pepper token: %w", err)
+	}
+	hashes := []string{pepperedHash(activeKid, activePepper, token)}
+	for _, kid := range s.peppers.KeyIDs() {
+		if kid == activeKid {
+			continue
+		}
+		pepper, err := s.peppers.Key(kid)
+		if err != nil {
+			// Pepper dihapus oleh refresh keyring yang berjalan bersamaan.
+			continue
+		}
+		hashes = append(hashes, pepperedHash(kid, pepper, token))
+	}
+	if s.acceptLegacyHash {
+		hashes = append(hashes, hashToken(token))
+	}
+	return hashes, nil
+}
+
+// lookupHash memeriksa token dengan canonicalToken, lalu mencari hash token yang tersimpan di
+// Redis. Jika tidak ada yang tersimpan, hash pepper aktif dikembalikan sehingga operasi
+// berikutnya menghasilkan ErrInvalidToken. Untuk token bertanda tangan, ID undangan dan
+// tenant pada klaim harus sama dengan undangan yang tersimpan.
+func (s *invitationService) lookupHash(ctx context.Context, token string) (string, error) {
+	token, claims, err := s.canonicalToken(token)
+	if err != nil {
+		return "", err
+	}
+	hashes, err := s.candidateHashes(token)
+	if err != nil {
+		return "", err
+	}
+	tokenHash, err := s.findStoredHash(ctx, hashes)
+	if err != nil || claims == nil {
+		return tokenHash, err
+	}
+
+	data, err := s.readInvitation(ctx, tokenHash)
+	switch {
+	case errors.Is(err, ErrInvalidToken):
+		return tokenHash, nil
+	case err != nil:
+		return "", err
+	case data.ID != claims.InvitationID || data.TenantID != claims.TenantID:
+		log.Warn().Str("invitation_id", data.ID).Str("tenant_id", data.TenantID).Msg("Klaim token undangan tidak cocok dengan undangan yang tersimpan")
+		return "", ErrInvalidToken
+	}
+	return tokenHash, nil
+}
+
+// findStoredHash mengembalikan hash pertama dari hashes yang tersimpan di Redis, atau
+// hashes[0] jika tidak ada.
+func (s *invitationService) findStoredHash(ctx context.Context, hashes []string) (string, error) {
+	if len(hashes) == 1 {
+		return hashes[0], nil
+	}
+
+	keys := make([]string, len(hashes))
+	for i, hash := range hashes {
+		keys[i] = tokenKey(hash)
+	}
+	values, err := s.redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return "", err
+	}
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		if s.acceptLegacyHash && i == len(hashes)-1 {
+			legacyTokenLookups.Inc()
+		}
+		return hashes[i], nil
+	}
+	return hashes[0], nil
+}
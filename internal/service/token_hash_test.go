@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvitationService_TokenPepper(t *testing.T) {
+	ctx := context.Background()
+	pepperA := []byte("pepper-a-0123456789abcdef0123456")
+	pepperB := []byte("pepper-b-0123456789abcdef0123456")
+	newPeppers := func(t *testing.T, active string, peppers map[string][]byte) *StaticKeyring {
+		keyring, err := NewStaticKeyring(active, peppers)
+		require.NoError(t, err)
+		return keyring
+	}
+	create := func(t *testing.T, svc *invitationService, token, email string) {
+		svc.tokenGenerator = &MockTokenGenerator{TokenToReturn: token}
+		_, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: email, Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+	}
+
+	t.Run("New Tokens Use Active Pepper", func(t *testing.T) {
+		svc, mr := newDeliveringService(t, nil)
+		WithTokenPepper(newPeppers(t, "a", map[string][]byte{"a": pepperA}), true)(svc)
+		create(t, svc, "peppered-token", "user@example.com")
+
+		assert.False(t, mr.Exists(tokenKey(hashToken("peppered-token"))))
+		assert.True(t, mr.Exists(tokenKey(pepperedHash("a", pepperA, "peppered-token"))))
+		_, err := svc.ValidateInvitation(ctx, "peppered-token")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Legacy Hashes Are Read Until Disabled", func(t *testing.T) {
+		svc, _ := newDeliveringService(t, nil)
+		create(t, svc, "legacy-token-1", "first@example.com")
+		create(t, svc, "legacy-token-2", "second@example.com")
+		peppers := newPeppers(t, "a", map[string][]byte{"a": pepperA})
+		WithTokenPepper(peppers, true)(svc)
+
+		before := testutil.ToFloat64(legacyTokenLookups)
+		reservation, err := svc.ReserveInvitation(ctx, "legacy-token-1")
+		require.NoError(t, err)
+		data, err := svc.CommitInvitation(ctx, "legacy-token-1", reservation.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "first@example.com", data.Email)
+		assert.Equal(t, before+2, testutil.ToFloat64(legacyTokenLookups))
+
+		WithTokenPepper(peppers, false)(svc)
+		_, err = svc.PreviewInvitation(ctx, "legacy-token-2")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Pepper Rotation", func(t *testing.T) {
+		svc, _ := newDeliveringService(t, nil)
+		WithTokenPepper(newPeppers(t, "a", map[string][]byte{"a": pepperA}), false)(svc)
+		create(t, svc, "rotated-token", "user@example.com")
+
+		WithTokenPepper(newPeppers(t, "b", map[string][]byte{"a": pepperA, "b": pepperB}), false)(svc)
+		_, err := svc.PreviewInvitation(ctx, "rotated-token")
+		assert.NoError(t, err)
+
+		WithTokenPepper(newPeppers(t, "b", map[string][]byte{"b": pepperB}), false)(svc)
+		_, err = svc.PreviewInvitation(ctx, "rotated-token")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
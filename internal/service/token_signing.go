@@ -0,0 +1,163 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// MinKeySize adalah panjang minimum setiap kunci di Keyring, dalam byte.
+const MinKeySize = 32
+
+// ErrUnknownKey dikembalikan Keyring ketika ID kunci tidak dikenal.
+var ErrUnknownKey = errors.New("ID kunci tidak dikenal")
+
+// Keyring menyediakan kunci HMAC rahasia beserta ID-nya, dipakai untuk menandatangani token
+// maupun sebagai pepper hash token. Kunci lama tetap tersedia melalui Key selama data yang
+// dibuat dengannya masih berlaku, sehingga kunci dapat dirotasi tanpa membatalkan undangan
+// yang sudah dikirim.
+type Keyring interface {
+	// ActiveKey mengembalikan kunci untuk menandatangani token baru.
+	ActiveKey() (kid string, key []byte, err error)
+	// Key mengembalikan kunci dengan ID kid, atau ErrUnknownKey.
+	Key(kid string) ([]byte, error)
+	// KeyIDs mengembalikan ID semua kunci, terurut.
+	KeyIDs() []string
+}
+
+// StaticKeyring adalah Keyring dengan kunci tetap dari konfigurasi.
+type StaticKeyring struct {
+	active string
+	keys   map[string][]byte
+}
+
+// NewStaticKeyring membuat keyring dengan kunci aktif active. Setiap kunci minimal
+// MinKeySize byte.
+func NewStaticKeyring(active string, keys map[string][]byte) (*StaticKeyring, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("kunci aktif %q tidak ada di keyring", active)
+	}
+	for kid, key := range keys {
+		if len(key) < MinKeySize {
+			return nil, fmt.Errorf("kunci %q lebih pendek dari %d byte", kid, MinKeySize)
+		}
+	}
+	return &StaticKeyring{active: active, keys: keys}, nil
+}
+
+// ParseKeys membaca daftar kunci Keyring dalam format "kid1:base64,kid2:base64".
+func ParseKeys(spec string) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		kid, encoded, ok := strings.Cut(entry, ":")
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if !ok || err != nil || strings.TrimSpace(kid) == "" {
+			return nil, fmt.Errorf("format kunci tidak valid pada entri %q", strings.TrimSpace(kid))
+		}
+		keys[strings.TrimSpace(kid)] = key
+	}
+	return keys, nil
+}
+
+func (k *StaticKeyring) ActiveKey() (string, []byte, error) {
+	return k.active, k.keys[k.active], nil
+}
+
+func (k *StaticKeyring) Key(kid string) ([]byte, error) {
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+func (k *StaticKeyring) KeyIDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for kid := range k.keys {
+		ids = append(ids, kid)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// invitationClaims adalah isi token bertanda tangan: jti acak agar setiap token unik, sub
+// berisi ID undangan, tid berisi tenant, dan exp berisi masa berlaku undangan.
+type invitationClaims struct {
+	TenantID string `json:"tid"`
+	jwt.RegisteredClaims
+}
+
+// SignedTokenGenerator membuat token JWT HS256 yang menyematkan ID undangan, tenant dan masa
+// berlaku, dengan ID kunci pada header kid. Tanda tangan dan masa berlaku diperiksa sebelum
+// Redis diakses, sehingga token yang salah ketik atau dipalsukan langsung ditolak.
+type SignedTokenGenerator struct {
+	keyring      Keyring
+	acceptLegacy bool
+	now          func() time.Time
+}
+
+// NewSignedTokenGenerator membuat generator token bertanda tangan. Dengan acceptLegacy, token
+// UUID lama yang dibuat sebelum penandatanganan diaktifkan tetap diteruskan ke Redis.
+func NewSignedTokenGenerator(keyring Keyring, acceptLegacy bool) *SignedTokenGenerator {
+	return &SignedTokenGenerator{keyring: keyring, acceptLegacy: acceptLegacy, now: time.Now}
+}
+
+func (g *SignedTokenGenerator) Generate(claims TokenClaims) (string, error) {
+	kid, key, err := g.keyring.ActiveKey()
+	if err != nil {
+		return "", fmt.Errorf("gagal mengambil kunci penandatangan token: %w", err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, invitationClaims{
+		TenantID: claims.TenantID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   claims.InvitationID,
+			ExpiresAt: jwt.NewNumericDate(claims.ExpiresAt),
+		},
+	})
+	token.Header["kid"] = kid
+	return token.SignedString(key)
+}
+
+// Verify memeriksa tanda tangan dan masa berlaku token. Token UUID lama mengembalikan klaim
+// nil jika diterima. Error selain ErrInvalidToken berarti keyring tidak dapat dibaca.
+func (g *SignedTokenGenerator) Verify(token string) (*TokenClaims, error) {
+	if strings.Count(token, ".") != 2 {
+		if g.acceptLegacy {
+			return nil, nil
+		}
+		return nil, ErrInvalidToken
+	}
+
+	var claims invitationClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return g.keyring.Key(kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(g.now),
+	)
+	if errors.Is(err, jwt.ErrTokenUnverifiable) && !errors.Is(err, ErrUnknownKey) {
+		return nil, err
+	}
+	if err != nil {
+		log.Debug().Err(err).Msg("Token undangan bertanda tangan ditolak")
+		return nil, ErrInvalidToken
+	}
+	return &TokenClaims{
+		InvitationID: claims.Subject,
+		TenantID:     claims.TenantID,
+		ExpiresAt:    claims.ExpiresAt.Time.UTC(),
+	}, nil
+}
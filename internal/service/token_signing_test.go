@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-invitation-service/internal/client"
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedTokenGenerator(t *testing.T) {
+	ctx := context.Background()
+	keyA := []byte("0123456789abcdef0123456789abcdef")
+	keyB := []byte("fedcba9876543210fedcba9876543210")
+	newGenerator := func(t *testing.T, active string, keys map[string][]byte, acceptLegacy bool) *SignedTokenGenerator {
+		keyring, err := NewStaticKeyring(active, keys)
+		require.NoError(t, err)
+		g := NewSignedTokenGenerator(keyring, acceptLegacy)
+		g.now = func() time.Time { return fixedNow }
+		return g
+	}
+	claims := TokenClaims{InvitationID: "inv-1", TenantID: "tenant-1", ExpiresAt: fixedNow.Add(time.Hour)}
+
+	t.Run("Round Trip And Rotation", func(t *testing.T) {
+		old := newGenerator(t, "a", map[string][]byte{"a": keyA}, false)
+		token, err := old.Generate(claims)
+		require.NoError(t, err)
+
+		// Setelah rotasi, token dari kunci lama tetap berlaku selama kunci tersebut masih ada.
+		rotated := newGenerator(t, "b", map[string][]byte{"a": keyA, "b": keyB}, false)
+		verified, err := rotated.Verify(token)
+		require.NoError(t, err)
+		assert.Equal(t, claims, *verified)
+
+		retired := newGenerator(t, "b", map[string][]byte{"b": keyB}, false)
+		_, err = retired.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Rejects Tampered And Expired Tokens", func(t *testing.T) {
+		g := newGenerator(t, "a", map[string][]byte{"a": keyA}, false)
+		token, err := g.Generate(claims)
+		require.NoError(t, err)
+
+		_, err = g.Verify(token[:len(token)-2] + "xx")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+
+		g.now = func() time.Time { return claims.ExpiresAt.Add(time.Minute) }
+		_, err = g.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Legacy Tokens", func(t *testing.T) {
+		verified, err := newGenerator(t, "a", map[string][]byte{"a": keyA}, true).Verify("3f1c2d4e-0000-4000-8000-000000000000")
+		require.NoError(t, err)
+		assert.Nil(t, verified)
+
+		_, err = newGenerator(t, "a", map[string][]byte{"a": keyA}, false).Verify("3f1c2d4e-0000-4000-8000-000000000000")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Invalid Keyring", func(t *testing.T) {
+		_, err := NewStaticKeyring("a", map[string][]byte{"a": []byte("short")})
+		assert.Error(t, err)
+		_, err = NewStaticKeyring("missing", map[string][]byte{"a": keyA})
+		assert.Error(t, err)
+
+		keys, err := ParseKeys("a:" + base64.StdEncoding.EncodeToString(keyA))
+		require.NoError(t, err)
+		assert.Equal(t, keyA, keys["a"])
+		_, err = ParseKeys("a:not-base64!")
+		assert.Error(t, err)
+	})
+
+	t.Run("Service Verifies Before Redis", func(t *testing.T) {
+		var sentToken string
+		mockPublisher := new(MockQueuePublisher)
+		mockPublisher.On("Enqueue", ctx, mock.Anything).Run(func(args mock.Arguments) {
+			link := args.Get(1).(client.NotificationPayload).TemplateData["InvitationLink"].(string)
+			sentToken = link[strings.Index(link, "token=")+len("token="):]
+		}).Return(nil).Once()
+		g := newGenerator(t, "a", map[string][]byte{"a": keyA}, false)
+		svc, _ := newMiniredisService(t, mockPublisher, g)
+
+		invitation, err := svc.CreateInvitation(ctx, CreateInvitationRequest{Email: "user@example.com", Role: "viewer", TenantID: "tenant-1", InviterID: "inviter-1"})
+		require.NoError(t, err)
+		verified, err := g.Verify(sentToken)
+		require.NoError(t, err)
+		assert.Equal(t, invitation.ID, verified.InvitationID)
+		assert.Equal(t, invitation.ExpiresAt, verified.ExpiresAt)
+
+		// Tanpa ekspektasi apa pun, redismock gagal jika token palsu sampai ke Redis.
+		redisClient, mockRedis := redismock.NewClientMock()
+		offline := newTestService(redisClient, mockPublisher, g, 24)
+		_, err = offline.ValidateInvitation(ctx, sentToken+"x")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		_, err = offline.PreviewInvitation(ctx, "typo.in.token")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		assert.NoError(t, mockRedis.ExpectationsWereMet())
+
+		data, err := svc.ValidateInvitation(ctx, sentToken)
+		require.NoError(t, err)
+		assert.Equal(t, invitation.ID, data.ID)
+	})
+
+	t.Run("Claims Must Match Stored Invitation", func(t *testing.T) {
+		g := newGenerator(t, "a", map[string][]byte{"a": keyA}, false)
+		svc, mr := newMiniredisService(t, new(MockQueuePublisher), g)
+		token, err := g.Generate(claims)
+		require.NoError(t, err)
+		hashes, err := svc.candidateHashes(token)
+		require.NoError(t, err)
+
+		store := func(data InvitationData) {
+			payload, err := json.Marshal(data)
+			require.NoError(t, err)
+			require.NoError(t, mr.Set(tokenKey(hashes[0]), string(payload)))
+		}
+		for _, stored := range []InvitationData{
+			{ID: "inv-2", TenantID: claims.TenantID, Email: "other@example.com"},
+			{ID: claims.InvitationID, TenantID: "tenant-2", Email: "other@example.com"},
+		} {
+			store(stored)
+			_, err = svc.PreviewInvitation(ctx, token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+			_, err = svc.ValidateInvitation(ctx, token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+			assert.True(t, mr.Exists(tokenKey(hashes[0])), "undangan yang tidak cocok tidak boleh dikonsumsi")
+		}
+
+		store(InvitationData{ID: claims.InvitationID, TenantID: claims.TenantID, Email: "user@example.com"})
+		data, err := svc.PreviewInvitation(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, claims.InvitationID, data.ID)
+	})
+}
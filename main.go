@@ -8,9 +8,11 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	commonauth "github.com/Lumina-Enterprise-Solutions/prism-common-libs/auth"
 	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/client"
 	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/enhanced_logger"
 	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/telemetry"
@@ -51,8 +53,14 @@ func main() {
 		}
 	}()
 
-	// BARU: Setup RabbitMQ Publisher
-	queuePublisher, err := invitationclient.NewQueuePublisher(cfg.RabbitMQURL)
+	// BARU: Setup RabbitMQ Publisher. Koneksi yang terputus dipulihkan otomatis di latar belakang.
+	publisherOpts := []invitationclient.PublisherOption{
+		invitationclient.WithPublishBuffer(cfg.RabbitMQPublishBufferSize, time.Duration(cfg.RabbitMQPublishBufferTimeoutSeconds)*time.Second),
+	}
+	if cfg.RabbitMQDisconnectedMode == "fail_fast" {
+		publisherOpts = append(publisherOpts, invitationclient.WithFailFast())
+	}
+	queuePublisher, err := invitationclient.NewQueuePublisher(cfg.RabbitMQURL, publisherOpts...)
 	if err != nil {
 		serviceLogger.Fatal().Err(err).Msg("Gagal terhubung ke RabbitMQ")
 	}
@@ -62,13 +70,197 @@ func main() {
 		}
 	}()
 
+	// Publisher domain event siklus hidup undangan (topic exchange terpisah dari notifikasi).
+	// Event bersifat best effort dan dipublikasikan di jalur request serta chunk job, sehingga
+	// publisher-nya tidak pernah menunggu koneksi pulih.
+	eventPublisher, err := invitationclient.NewEventPublisher(cfg.RabbitMQURL, invitationclient.WithFailFast())
+	if err != nil {
+		serviceLogger.Fatal().Err(err).Msg("Gagal membuat publisher domain event")
+	}
+	defer func() {
+		if err := eventPublisher.Close(); err != nil {
+			serviceLogger.Error().Err(err).Msg("Gagal menutup publisher domain event dengan benar")
+		}
+	}()
+
 	// Inisialisasi service dan handler dengan publisher baru.
-	realTokenGenerator := &service.UUIDTokenGenerator{}
-	invitationService := service.NewInvitationService(redisClient, queuePublisher, realTokenGenerator, cfg.InvitationTTL)
-	invitationHandler := handler.NewInvitationHandler(invitationService)
+	keySource, err := newKeySource(cfg)
+	if err != nil {
+		serviceLogger.Fatal().Err(err).Msg("Gagal menyiapkan sumber kunci token undangan")
+	}
+	keyRefreshInterval := time.Duration(cfg.InvitationKeysRefreshSeconds) * time.Second
+	var refreshingKeyrings []*service.RefreshingKeyring
+	var realTokenGenerator service.TokenGenerator = &service.UUIDTokenGenerator{}
+	switch {
+	case keySource != nil:
+		keyring, err := service.NewRefreshingKeyring(keySource(service.SigningKeyFields), keyRefreshInterval)
+		if err != nil {
+			serviceLogger.Fatal().Err(err).Msg("Gagal memuat kunci penandatangan token undangan")
+		}
+		refreshingKeyrings = append(refreshingKeyrings, keyring)
+		realTokenGenerator = service.NewSignedTokenGenerator(keyring, cfg.InvitationTokenAcceptLegacy)
+	case cfg.InvitationTokenKeys != "":
+		signingKeys, err := service.ParseKeys(cfg.InvitationTokenKeys)
+		if err != nil {
+			serviceLogger.Fatal().Err(err).Msg("INVITATION_TOKEN_KEYS tidak valid")
+		}
+		keyring, err := service.NewStaticKeyring(cfg.InvitationTokenActiveKey, signingKeys)
+		if err != nil {
+			serviceLogger.Fatal().Err(err).Msg("INVITATION_TOKEN_KEYS atau kunci aktif token undangan tidak valid")
+		}
+		realTokenGenerator = service.NewSignedTokenGenerator(keyring, cfg.InvitationTokenAcceptLegacy)
+	default:
+		serviceLogger.Warn().Msg("Token undangan tidak ditandatangani: sumber kunci dan INVITATION_TOKEN_KEYS kosong")
+	}
+	var tokenPeppers service.Keyring
+	switch {
+	case keySource != nil:
+		keyring, err := service.NewRefreshingKeyring(keySource(service.PepperKeyFields), keyRefreshInterval)
+		if err != nil {
+			serviceLogger.Fatal().Err(err).Msg("Gagal memuat pepper token undangan")
+		}
+		refreshingKeyrings = append(refreshingKeyrings, keyring)
+		tokenPeppers = keyring
+	case cfg.InvitationTokenPeppers != "":
+		peppers, err := service.ParseKeys(cfg.InvitationTokenPeppers)
+		if err != nil {
+			serviceLogger.Fatal().Err(err).Msg("INVITATION_TOKEN_PEPPERS tidak valid")
+		}
+		if tokenPeppers, err = service.NewStaticKeyring(cfg.InvitationTokenActivePepper, peppers); err != nil {
+			serviceLogger.Fatal().Err(err).Msg("INVITATION_TOKEN_PEPPERS atau pepper aktif token undangan tidak valid")
+		}
+	default:
+		serviceLogger.Warn().Msg("Hash token undangan tanpa pepper: sumber kunci dan INVITATION_TOKEN_PEPPERS kosong")
+	}
+	serviceOpts := []service.Option{
+		service.WithResendPolicy(cfg.InvitationResendMax, time.Duration(cfg.InvitationResendCooldownMinutes)*time.Minute),
+		service.WithReservationLease(time.Duration(cfg.InvitationReservationLeaseSeconds) * time.Second),
+		service.WithEventPublisher(eventPublisher),
+		service.WithRateLimits(service.RateLimits{
+			Window:  time.Duration(cfg.RateLimitWindowSeconds) * time.Second,
+			Tenant:  cfg.RateLimitPerTenant,
+			Inviter: cfg.RateLimitPerInviter,
+			Domain:  cfg.RateLimitPerDomain,
+		}),
+	}
+	if tokenPeppers != nil {
+		serviceOpts = append(serviceOpts, service.WithTokenPepper(tokenPeppers, cfg.InvitationTokenAcceptUnpeppered))
+	}
+	switch {
+	case cfg.RoleServiceURL != "":
+		serviceOpts = append(serviceOpts, service.WithRoleValidator(service.NewHTTPRoleValidator(cfg.RoleServiceURL,
+			time.Duration(cfg.RoleServiceTimeoutSeconds)*time.Second, time.Duration(cfg.RoleCacheSeconds)*time.Second)))
+	case cfg.InvitationRoles != "":
+		serviceOpts = append(serviceOpts, service.WithRoleValidator(service.NewStaticRoleValidator(strings.Split(cfg.InvitationRoles, ","))))
+	default:
+		serviceLogger.Warn().Msg("Validasi role undangan nonaktif: role_service_url dan invitation_roles kosong")
+	}
+	if cfg.SeatServiceURL != "" {
+		serviceOpts = append(serviceOpts, service.WithSeatProvider(service.NewHTTPSeatProvider(cfg.SeatServiceURL,
+			time.Duration(cfg.SeatServiceTimeoutSeconds)*time.Second)))
+	} else {
+		seatProvider, err := service.NewConfigSeatProvider(cfg.SeatLimitDefault, cfg.SeatLimits)
+		if err != nil {
+			serviceLogger.Fatal().Err(err).Msg("Konfigurasi seat_limits tidak valid")
+		}
+		serviceOpts = append(serviceOpts, service.WithSeatProvider(seatProvider))
+	}
+	if cfg.ShortCodesEnabled {
+		shortCodes, err := service.NewShortCodeGenerator(cfg.ShortCodeLength)
+		if err != nil {
+			serviceLogger.Fatal().Err(err).Msg("Konfigurasi short_code_length tidak valid")
+		}
+		serviceOpts = append(serviceOpts, service.WithShortCodes(shortCodes))
+	}
+	tenantExpiryBounds, err := service.ParseExpiryBounds(cfg.InvitationTTLBounds)
+	if err != nil {
+		serviceLogger.Fatal().Err(err).Msg("Konfigurasi invitation_ttl_bounds tidak valid")
+	}
+	expiryBounds := service.ExpiryBounds{
+		Min: time.Duration(cfg.InvitationTTLMinHours) * time.Hour,
+		Max: time.Duration(cfg.InvitationTTLMaxHours) * time.Hour,
+	}
+	if err := expiryBounds.Validate(time.Duration(cfg.InvitationTTL) * time.Hour); err != nil {
+		serviceLogger.Fatal().Err(err).Msg("Konfigurasi invitation_ttl_hours atau batasnya tidak valid")
+	}
+	serviceOpts = append(serviceOpts, service.WithExpiryBounds(expiryBounds, tenantExpiryBounds))
+	tenantLinkTemplates, err := service.ParseLinkTemplates(cfg.InvitationLinkTemplates)
+	if err != nil {
+		serviceLogger.Fatal().Err(err).Msg("Konfigurasi invitation_link_templates tidak valid")
+	}
+	linkTemplates, err := service.NewLinkTemplates(cfg.InvitationLinkTemplate, tenantLinkTemplates)
+	if err != nil {
+		serviceLogger.Fatal().Err(err).Msg("Template tautan undangan tidak valid")
+	}
+	serviceOpts = append(serviceOpts, service.WithLinkTemplates(linkTemplates))
+	invitationService := service.NewInvitationService(redisClient, queuePublisher, realTokenGenerator, cfg.InvitationTTL, serviceOpts...)
+	jobService := service.NewJobService(redisClient)
+	tokenGuard := service.NewTokenGuard(redisClient, service.GuardLimits{
+		Window:            time.Duration(cfg.TokenGuardWindowSeconds) * time.Second,
+		MaxFailures:       cfg.TokenGuardMaxFailures,
+		BaseLockout:       time.Duration(cfg.TokenGuardBaseLockoutSeconds) * time.Second,
+		MaxLockout:        time.Duration(cfg.TokenGuardMaxLockoutSeconds) * time.Second,
+		GlobalWindow:      time.Duration(cfg.TokenGuardGlobalWindowSeconds) * time.Second,
+		GlobalMaxFailures: cfg.TokenGuardGlobalMaxFailures,
+		GlobalLockout:     time.Duration(cfg.TokenGuardGlobalLockoutSeconds) * time.Second,
+	})
+	handlerOpts := []handler.HandlerOption{
+		handler.WithJobService(jobService),
+		handler.WithTokenGuard(tokenGuard),
+	}
+	if cfg.RBACUserServiceAddr != "" {
+		rbac, err := commonauth.NewRBACMiddleware(cfg.RBACUserServiceAddr, time.Duration(cfg.RBACCacheTTLSeconds)*time.Second)
+		if err != nil {
+			serviceLogger.Fatal().Err(err).Msg("Gagal membuat RBAC middleware")
+		}
+		defer func() {
+			if err := rbac.Close(); err != nil {
+				serviceLogger.Error().Err(err).Msg("Gagal menutup koneksi RBAC ke user-service dengan benar")
+			}
+		}()
+		handlerOpts = append(handlerOpts, handler.WithPermissionMiddleware(rbac.RequirePermission))
+	} else {
+		handlerOpts = append(handlerOpts, handler.WithAuthorizer(handler.NewClaimsAuthorizer(cfg.PermissionsClaim)))
+	}
+	if cfg.ShortCodesEnabled {
+		handlerOpts = append(handlerOpts, handler.WithShortCodeGuard(service.NewShortCodeGuard(redisClient, service.GuardLimits{
+			Window:            time.Duration(cfg.ShortCodeGuardWindowSeconds) * time.Second,
+			MaxFailures:       cfg.ShortCodeGuardMaxFailures,
+			GlobalMaxFailures: cfg.ShortCodeGuardGlobalMaxFailures,
+		})))
+	}
+	invitationHandler := handler.NewInvitationHandler(invitationService, handlerOpts...)
+
+	// Background worker berhenti ketika workerCtx dibatalkan saat shutdown.
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	expirySweeper := service.NewExpirySweeper(redisClient, eventPublisher, time.Duration(cfg.InvitationExpirySweepSeconds)*time.Second)
+	go expirySweeper.Run(workerCtx)
+	// Relay outbox mengirim ulang email undangan yang tertunda saat RabbitMQ tidak tersedia.
+	outboxRelay := service.NewOutboxRelay(redisClient, queuePublisher, time.Duration(cfg.OutboxPollIntervalSeconds)*time.Second, cfg.OutboxMaxAttempts)
+	go outboxRelay.Run(workerCtx)
+	// Worker job undangan massal; state job di Redis sehingga replika mana pun dapat melanjutkannya.
+	jobRunner := service.NewJobRunner(redisClient, invitationService, cfg.InvitationJobWorkers)
+	go jobRunner.Run(workerCtx)
+	// Kunci dari Vault atau berkas lokal dimuat ulang berkala agar rotasi tidak memerlukan restart.
+	for _, keyring := range refreshingKeyrings {
+		go keyring.Run(workerCtx)
+	}
 
 	// Setup Gin Router
 	router := gin.Default()
+	// c.ClientIP() menjadi kunci penguncian TokenGuard dan SourceIP undangan, sehingga hanya
+	// ingress yang dikonfigurasi yang dipercaya untuk mengisi X-Forwarded-For.
+	var trustedProxies []string
+	if cfg.TrustedProxies != "" {
+		trustedProxies = strings.Split(cfg.TrustedProxies, ",")
+		for i := range trustedProxies {
+			trustedProxies[i] = strings.TrimSpace(trustedProxies[i])
+		}
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		serviceLogger.Fatal().Err(err).Msg("Konfigurasi trusted_proxies tidak valid")
+	}
 	router.Use(otelgin.Middleware(cfg.ServiceName))
 	p := ginprometheus.NewPrometheus("gin")
 	p.Use(router)
@@ -76,8 +268,8 @@ func main() {
 	// --- Routes ---
 	group := router.Group("/invitations")
 	group.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "healthy"}) })
-	group.POST("", invitationHandler.CreateInvitation)
-	group.POST("/validate", invitationHandler.ValidateInvitation)
+	// Izin setiap route dideklarasikan di tabel kebijakan InvitationHandler.
+	invitationHandler.RegisterRoutes(group, commonauth.JWTMiddleware(redisClient))
 
 	// Setup Consul Service Discovery
 	regInfo := client.ServiceRegistrationInfo{
@@ -116,3 +308,27 @@ func main() {
 	}
 	enhanced_logger.LogShutdown(cfg.ServiceName)
 }
+
+// newKeySource memilih penyimpanan kunci token undangan: Vault jika invitation_keys_vault_path
+// diisi, berkas lokal jika invitation_keys_file diisi, atau nil jika keduanya kosong.
+func newKeySource(cfg *config.Config) (func(service.KeyFields) service.KeySource, error) {
+	switch {
+	case cfg.InvitationKeysVaultPath != "" && cfg.InvitationKeysVaultEngine == "transit":
+		return func(fields service.KeyFields) service.KeySource {
+			return service.NewVaultTransitKeySource(cfg.VaultAddr, cfg.VaultToken, cfg.InvitationKeysVaultPath, fields, service.DefaultVaultTimeout)
+		}, nil
+	case cfg.InvitationKeysVaultPath != "":
+		vaultClient, err := client.NewVaultClient(cfg.VaultAddr, cfg.VaultToken)
+		if err != nil {
+			return nil, err
+		}
+		return func(fields service.KeyFields) service.KeySource {
+			return service.NewVaultKeySource(vaultClient, cfg.InvitationKeysVaultPath, fields)
+		}, nil
+	case cfg.InvitationKeysFile != "":
+		return func(fields service.KeyFields) service.KeySource {
+			return service.NewFileKeySource(cfg.InvitationKeysFile, fields)
+		}, nil
+	}
+	return nil, nil
+}